@@ -48,15 +48,19 @@ func ProcessStream(in io.Reader, watcheePid int, watcheeArgs []string) {
 			call := calls[len(calls)-1-i]
 
 			f := call.Func
+			// :TRICKY: f.PkgName() is the last path element only, but
+			// in-app detection wants the full package path
+			pkg := sentry.PackagePath(f.String())
+
 			// NewStacktraceFrame
 			frame := &raven.StacktraceFrame{
 				Filename: call.SrcPath,
 				Function: f.Name(),
-				Module:   f.PkgName(),
+				Module:   pkg,
 
 				AbsolutePath: call.SrcPath,
 				Lineno:       call.Line,
-				InApp:        false,
+				InApp:        sentry.IsInApp(pkg),
 			}
 
 			frames = append(frames, frame)
@@ -0,0 +1,384 @@
+/*
+Package fakesentry is a local Sentry stand-in for integration tests and demos without network:
+it accepts events via store and envelope endpoints, checks auth, keeps events in memory
+and as JSON lines, and answers queries about them.
+*/
+package fakesentry
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	urlModule "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muravjov/slog/stress"
+)
+
+// Event is an event as the server got it
+type Event struct {
+	ProjectID string    `json:"project_id"`
+	Received  time.Time `json:"received"`
+	// "store" or "envelope"
+	Protocol string                 `json:"protocol"`
+	Event    map[string]interface{} `json:"event"`
+}
+
+func (e *Event) str(key string) string {
+	s, _ := e.Event[key].(string)
+	return s
+}
+
+func (e *Event) Level() string   { return e.str("level") }
+func (e *Event) Message() string { return e.str("message") }
+
+type Server struct {
+	// events are appended as JSON lines, if not nil
+	Log io.Writer
+
+	mu     sync.Mutex
+	events []Event
+	// public key => secret; empty secret means any, no keys means any auth
+	keys map[string]string
+}
+
+func NewServer() *Server {
+	return &Server{
+		keys: map[string]string{},
+	}
+}
+
+// AllowDSN() makes the server accept keys of dsn only, call it for every DSN expected
+func (s *Server) AllowDSN(dsn string) error {
+	uri, err := urlModule.Parse(dsn)
+	if err != nil {
+		return err
+	}
+	if uri.User == nil {
+		return fmt.Errorf("no public key in DSN %s", dsn)
+	}
+	secret, _ := uri.User.Password()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[uri.User.Username()] = secret
+	return nil
+}
+
+// Events() returns received events, oldest first
+func (s *Server) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// Routes() returns endpoints: store, envelope and events query
+func (s *Server) Routes() stress.Routes {
+	return stress.Routes{
+		// url(r'^api/(?P<project_id>[\w_-]+)/store/$', api.StoreView.as_view(), name='sentry-api-store')
+		stress.NewRoute(`^/api/(?P<project_id>[\w_-]+)/store/$`, func(w http.ResponseWriter, r *http.Request, match stress.Match) {
+			s.serveIngest(w, r, match, "store")
+		}),
+		stress.NewRoute(`^/api/(?P<project_id>[\w_-]+)/envelope/$`, func(w http.ResponseWriter, r *http.Request, match stress.Match) {
+			s.serveIngest(w, r, match, "envelope")
+		}),
+		stress.NewRoute(`^/api/events/$`, s.serveQuery),
+	}
+}
+
+// Handler() is for http.Server or httptest.NewServer()
+func (s *Server) Handler() http.Handler {
+	return stress.NewServerHandler(s.Routes())
+}
+
+func fail(w http.ResponseWriter, code int, msg string) {
+	// raven and sentry.HTTPTransport report this header
+	w.Header().Set("X-Sentry-Error", msg)
+	stress.HttpError(w, code, msg)
+}
+
+// ParseAuth() parses header like "Sentry sentry_version=7, sentry_key=public, sentry_secret=secret"
+func ParseAuth(header string) map[string]string {
+	res := map[string]string{}
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "Sentry ") {
+		return res
+	}
+	for _, pair := range strings.Split(header[len("Sentry "):], ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 {
+			res[kv[0]] = kv[1]
+		}
+	}
+	return res
+}
+
+func (s *Server) checkAuth(r *http.Request) error {
+	header := r.Header.Get("X-Sentry-Auth")
+	if header == "" {
+		header = r.Header.Get("Authorization")
+	}
+	auth := ParseAuth(header)
+
+	// browsers pass auth via query
+	query := r.URL.Query()
+	for _, key := range []string{"sentry_key", "sentry_secret", "sentry_version"} {
+		if _, ok := auth[key]; !ok && query.Get(key) != "" {
+			auth[key] = query.Get(key)
+		}
+	}
+
+	key := auth["sentry_key"]
+	if key == "" {
+		return fmt.Errorf("missing sentry_key")
+	}
+	if auth["sentry_version"] == "" {
+		return fmt.Errorf("missing sentry_version")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.keys) == 0 {
+		return nil
+	}
+	secret, ok := s.keys[key]
+	if !ok {
+		return fmt.Errorf("unknown sentry_key: %s", key)
+	}
+	if secret != "" && auth["sentry_secret"] != secret {
+		return fmt.Errorf("bad sentry_secret")
+	}
+	return nil
+}
+
+// readBody() undoes Content-Encoding
+func readBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		return nil, fmt.Errorf("unknown Content-Encoding: %s", r.Header.Get("Content-Encoding"))
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// DecodeStore() decodes store request body: JSON or base64 of zlib of JSON, as raven sends it
+func DecodeStore(body []byte) (map[string]interface{}, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) != 0 && body[0] != '{' {
+		compressed, err := base64.StdEncoding.DecodeString(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("bad base64: %s", err)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("bad zlib: %s", err)
+		}
+		body, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("bad zlib: %s", err)
+		}
+	}
+
+	var event map[string]interface{}
+	err := json.Unmarshal(body, &event)
+	return event, err
+}
+
+// DecodeEnvelope() returns event items of envelope, see
+// https://develop.sentry.dev/sdk/envelopes/
+func DecodeEnvelope(body []byte) ([]map[string]interface{}, error) {
+	reader := bufio.NewReader(bytes.NewReader(body))
+	readLine := func() ([]byte, error) {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) != 0 {
+			err = nil
+		}
+		return bytes.TrimSuffix(line, []byte("\n")), err
+	}
+
+	// envelope header
+	if _, err := readLine(); err != nil {
+		return nil, fmt.Errorf("no envelope header: %s", err)
+	}
+
+	var events []map[string]interface{}
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var itemHeader struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &itemHeader); err != nil {
+			return nil, fmt.Errorf("bad item header: %s", err)
+		}
+
+		var payload []byte
+		if itemHeader.Length != nil {
+			payload = make([]byte, *itemHeader.Length)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return nil, fmt.Errorf("short item: %s", err)
+			}
+		} else {
+			payload, err = readLine()
+			if err != nil {
+				return nil, fmt.Errorf("no item payload: %s", err)
+			}
+		}
+
+		if itemHeader.Type != "event" {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("bad event: %s", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *Server) serveIngest(w http.ResponseWriter, r *http.Request, match stress.Match, protocol string) {
+	if r.Method != http.MethodPost {
+		fail(w, http.StatusMethodNotAllowed, "POST only")
+		return
+	}
+	if err := s.checkAuth(r); err != nil {
+		fail(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var events []map[string]interface{}
+	if protocol == "store" {
+		var event map[string]interface{}
+		event, err = DecodeStore(body)
+		events = append(events, event)
+	} else {
+		events, err = DecodeEnvelope(body)
+	}
+	if err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	projectID := match.GetArgumentsMap()["project_id"]
+	var id string
+	for _, event := range events {
+		ev := Event{
+			ProjectID: projectID,
+			Received:  time.Now(),
+			Protocol:  protocol,
+			Event:     event,
+		}
+		id = ev.str("event_id")
+
+		if err := s.add(ev); err != nil {
+			fail(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	stress.ServeJSON(w, http.StatusOK, stress.MarshalIndent(map[string]string{"id": id}))
+}
+
+func (s *Server) add(ev Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, ev)
+	if s.Log == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = s.Log.Write(append(line, '\n'))
+	return err
+}
+
+// /api/events/?project_id=1&level=error&message=<regexp>&limit=10 returns the latest
+// matching events, oldest first
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request, match stress.Match) {
+	query := r.URL.Query()
+
+	var messageRe *regexp.Regexp
+	if pattern := query.Get("message"); pattern != "" {
+		var err error
+		messageRe, err = regexp.Compile(pattern)
+		if err != nil {
+			fail(w, http.StatusBadRequest, fmt.Sprintf("bad message regexp: %s", err))
+			return
+		}
+	}
+	limit := 0
+	if l := query.Get("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			fail(w, http.StatusBadRequest, fmt.Sprintf("bad limit: %s", err))
+			return
+		}
+	}
+
+	res := []Event{}
+	for _, ev := range s.Events() {
+		if projectID := query.Get("project_id"); projectID != "" && ev.ProjectID != projectID {
+			continue
+		}
+		if level := query.Get("level"); level != "" && ev.Level() != level {
+			continue
+		}
+		if messageRe != nil && !messageRe.MatchString(ev.Message()) {
+			continue
+		}
+		res = append(res, ev)
+	}
+	if limit > 0 && len(res) > limit {
+		res = res[len(res)-limit:]
+	}
+
+	stress.ServeJSON(w, http.StatusOK, stress.MarshalIndent(res))
+}
@@ -0,0 +1,68 @@
+package fakesentry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	buf := &bytes.Buffer{}
+	s.Log = buf
+
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "http://", "http://public:secret@", 1) + "/1"
+	require.NoError(t, s.AllowDSN(dsn))
+
+	var errs []error
+	seh := func(err error) {
+		errs = append(errs, err)
+	}
+
+	// long message to make raven compress the packet
+	long := strings.Repeat("x", 2000)
+	for _, protocol := range []string{"", "?protocol=envelope"} {
+		client, err := sentry.NewClient(dsn+protocol, nil, seh)
+		require.NoError(t, err)
+
+		client.CaptureErrorAndWait("short error", nil, 0, raven.ERROR)
+		client.CaptureMessageAndWait("long warning "+long, nil, 0, &raven.Message{Message: "long warning %s"})
+	}
+	require.Empty(t, errs)
+
+	events := s.Events()
+	require.Len(t, events, 4)
+	require.Equal(t, []string{"store", "store", "envelope", "envelope"}, []string{
+		events[0].Protocol, events[1].Protocol, events[2].Protocol, events[3].Protocol,
+	})
+	require.Equal(t, "1", events[0].ProjectID)
+	require.Equal(t, "short error", events[0].Message())
+	require.Equal(t, "warning", events[3].Level())
+	require.Equal(t, 4, strings.Count(buf.String(), "\n"))
+
+	// bad secret
+	client, err := sentry.NewClient(strings.Replace(dsn, "secret", "wrong", 1), nil, seh)
+	require.NoError(t, err)
+	client.CaptureErrorAndWait("rejected error", nil, 0, raven.ERROR)
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0].Error(), "bad sentry_secret")
+
+	res, err := http.Get(server.URL + "/api/events/?level=warning&message=^long&limit=1")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var found []Event
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&found))
+	require.Len(t, found, 1)
+	require.Equal(t, "envelope", found[0].Protocol)
+}
@@ -0,0 +1,137 @@
+package slog
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+)
+
+// Common part of structured logger integrations, log/slog, zap and zerolog:
+// a record is a message with fields, some of fields are tags, others are extra
+
+// field name => tag name, like go-logging module tag
+var defaultFieldTags = map[string]string{"module": "module"}
+
+// fieldsRecord is a structured log record to report to Sentry
+type fieldsRecord struct {
+	Message string
+	// template of Message with Args, if the logger keeps it; Message is the template otherwise
+	Format string
+	Args   []interface{}
+	Level  raven.Severity
+	Time   time.Time
+	// call site, see runtime.Callers()
+	PC    uintptr
+	Tags  map[string]string
+	Extra map[string]interface{}
+	// reported as exception, if not nil
+	Err error
+	// explicit grouping, see sentry.Grouping
+	Fingerprint []string
+}
+
+// captureFields() reports the record like SentryBackend does: warnings are grouped
+// by message, errors have stacktrace; the message is the aggregation key, because
+// variable data belongs to fields
+func captureFields(ctx context.Context, client *sentry.Client, rec fieldsRecord) {
+	// like SentryBackend, don't report failures of reporting
+	if rec.Tags["module"] == "sentry.errors" {
+		return
+	}
+
+	format := rec.Format
+	if format == "" {
+		format = rec.Message
+	}
+	grouping := sentry.Grouping{
+		Format:      format,
+		Fingerprint: rec.Fingerprint,
+	}
+	iObject := &raven.Message{
+		Message: format,
+		Params:  rec.Args,
+	}
+
+	var packet *raven.Packet
+	if rec.Err != nil {
+		packet = sentry.NewExceptionPacketAt(rec.Err, rec.Message, rec.Tags, rec.PC, rec.Level, grouping)
+		packet.Interfaces = append(packet.Interfaces, iObject)
+	} else if rec.Level == raven.WARNING {
+		packet = sentry.NewMessagePacketAt(rec.Message, rec.Tags, rec.PC, iObject, grouping)
+	} else {
+		packet = sentry.NewErrorPacketAt(rec.Message, rec.Tags, rec.PC, rec.Level, grouping)
+	}
+	if !rec.Time.IsZero() {
+		packet.Timestamp = raven.Timestamp(rec.Time)
+	}
+	for key, val := range rec.Extra {
+		packet.Extra[key] = val
+	}
+
+	if client == nil {
+		client = sentry.DefaultClient()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client.CaptureAndWaitCtx(ctx, packet, rec.Tags)
+}
+
+// addFieldsBreadcrumb() keeps records less severe than reported ones
+func addFieldsBreadcrumb(rec fieldsRecord, category string, data map[string]interface{}) {
+	if !sentry.BreadcrumbsEnabledFor(rec.Level) {
+		return
+	}
+	if module := rec.Tags["module"]; module != "" {
+		category = module
+	}
+	if len(data) == 0 {
+		data = nil
+	}
+	// e.g. log/slog records may have no time
+	ts := rec.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	sentry.AddBreadcrumb(sentry.Breadcrumb{
+		Timestamp: sentry.UnixTime(ts),
+		Category:  category,
+		Message:   rec.Message,
+		Level:     rec.Level,
+		Data:      data,
+	})
+}
+
+// callerPC() returns the first caller out of the packages, for loggers, which
+// don't know the call site, like zerolog
+func callerPC(skipPrefixes ...string) uintptr {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	for _, pc := range pcs {
+		frames := runtime.CallersFrames([]uintptr{pc})
+		for {
+			fr, more := frames.Next()
+			skip := false
+			for _, prefix := range skipPrefixes {
+				if strings.HasPrefix(fr.Function, prefix) {
+					skip = true
+					break
+				}
+			}
+			// a caller may be inlined with the logger code
+			if !skip {
+				return pc
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	return 0
+}
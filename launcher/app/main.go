@@ -1,6 +1,7 @@
 package app
 
 import (
+	"debug/buildinfo"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -111,12 +112,65 @@ func DoMain(argv []string) {
 
 	dsn := getCheckString("sentry_dsn")
 	if dsn != "" {
+		// fail early, rather than lose post-mortems
+		if policy, ok := dct["sentry_check"].(string); ok {
+			checkPolicy, err := sentry.ParseCheckPolicy(policy)
+			if err != nil {
+				log.Printf("Bad sentry_check: %s", err)
+			}
+			sentry.SetCheck(sentry.CheckOptions{Policy: checkPolicy})
+		}
+		// for self-hosted Sentry behind an internal CA and an egress proxy
+		tlsFile, _ := dct["sentry_tls"].(string)
+		proxy, _ := dct["sentry_proxy"].(string)
+		if tlsFile != "" || proxy != "" {
+			err = sentry.SetHTTPOptions(&sentry.HTTPOptions{TLSConfigFile: tlsFile, Proxy: proxy})
+			if err != nil {
+				log.Printf("Can't set Sentry HTTP options: %s", err)
+			}
+		}
 		sentry.MustSetDSN(dsn)
 		// no go-logging dep
 		//SetupSentryLogger()
 		sentry.SetSEH(func(err error) {
 			log.Print(err)
 		})
+
+		// post-mortems are spooled, if Sentry is unavailable;
+		// launchee replays them
+		if spoolDir, ok := dct["sentry_spool"].(string); ok {
+			err = sentry.SetSpool(sentry.SpoolOptions{Dir: spoolDir})
+			if err != nil {
+				log.Printf("Can't set Sentry spool: %s", err)
+			}
+		}
+
+		// post-mortems are stamped like events of launchee; release is detected
+		// from build info of launchee binary, not of launcher
+		release, _ := dct["sentry_release"].(string)
+		if info, err := buildinfo.ReadFile(bin); err == nil {
+			if release == "" && os.Getenv("SENTRY_RELEASE") == "" {
+				release = sentry.ReleaseOf(info)
+			}
+			// in-app frames of post-mortems are of launchee main module too
+			sentry.SetInApp(sentry.InAppOptions{MainModule: info.Main.Path})
+		}
+		sentry.SetRelease(release)
+		if env, ok := dct["sentry_environment"].(string); ok {
+			sentry.SetEnvironment(env)
+		}
+		if serverName, ok := dct["sentry_server_name"].(string); ok {
+			sentry.SetServerName(serverName)
+		}
+
+		// launchee' stderr may contain passwords and tokens
+		if scrub, ok := dct["sentry_scrub"].(bool); ok && scrub {
+			opts := sentry.DefaultScrubOptions()
+			err = sentry.SetScrubber(&opts)
+			if err != nil {
+				log.Printf("Can't set Sentry scrubber: %s", err)
+			}
+		}
 	}
 
 	launcheeP := startLaunchee(launcheeArgv)
@@ -0,0 +1,138 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/muravjov/slog/sentry"
+	"github.com/sirupsen/logrus"
+)
+
+// LogrusHookOptions configures LogrusHook
+type LogrusHookOptions struct {
+	// reported levels, logrus.PanicLevel..logrus.WarnLevel if nil
+	Levels []logrus.Level
+	// field => tag name, other fields go to extra; nil means {"module": "module"}
+	Tags map[string]string
+	// nil means sentry.DefaultClient()
+	Client *sentry.Client
+}
+
+// LogrusHook reports logrus entries to Sentry via sentry.CaptureAndWait(), so they get
+// breadcrumbs, scope of entry context and SentryErrorHandler for failures.
+// logrus formats messages itself, so warnings are grouped by template only if
+// it's kept, see Warnf() and Logf()
+type LogrusHook struct {
+	opts LogrusHookOptions
+}
+
+func NewLogrusHook(opts LogrusHookOptions) *LogrusHook {
+	return &LogrusHook{
+		opts: opts,
+	}
+}
+
+var defaultLogrusLevels = []logrus.Level{
+	logrus.PanicLevel,
+	logrus.FatalLevel,
+	logrus.ErrorLevel,
+	logrus.WarnLevel,
+}
+
+func (hook *LogrusHook) Levels() []logrus.Level {
+	if hook.opts.Levels == nil {
+		return defaultLogrusLevels
+	}
+	return hook.opts.Levels
+}
+
+type logrusTemplateKey struct{}
+
+type logrusTemplate struct {
+	format string
+	args   []interface{}
+}
+
+// Logf() = entry.Logf(), but the format is kept for Sentry grouping; the format
+// travels via entry context, so that it doesn't get to local log
+func Logf(entry *logrus.Entry, level logrus.Level, format string, args ...interface{}) {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, logrusTemplateKey{}, logrusTemplate{format, args})
+	entry.WithContext(ctx).Logf(level, format, args...)
+}
+
+// Warnf() = logrus.Warnf(), but warnings are grouped by format, not by message
+func Warnf(format string, args ...interface{}) {
+	Logf(logrus.NewEntry(logrus.StandardLogger()), logrus.WarnLevel, format, args...)
+}
+
+// Errorf() = logrus.Errorf(), but the format is kept
+func Errorf(format string, args ...interface{}) {
+	Logf(logrus.NewEntry(logrus.StandardLogger()), logrus.ErrorLevel, format, args...)
+}
+
+func (hook *LogrusHook) Fire(entry *logrus.Entry) error {
+	tagNames := hook.opts.Tags
+	if tagNames == nil {
+		tagNames = defaultFieldTags
+	}
+
+	rec := fieldsRecord{
+		Message: entry.Message,
+		Level:   logrusSeverities[entry.Level],
+		Time:    entry.Time,
+		PC: callerPC("github.com/sirupsen/logrus", "github.com/muravjov/slog.(*LogrusHook)",
+			"github.com/muravjov/slog.Logf", "github.com/muravjov/slog.Warnf", "github.com/muravjov/slog.Errorf"),
+		Tags:  map[string]string{},
+		Extra: map[string]interface{}{},
+	}
+
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if tmpl, ok := ctx.Value(logrusTemplateKey{}).(logrusTemplate); ok {
+		rec.Format = tmpl.format
+		rec.Args = tmpl.args
+	}
+
+	for key, val := range entry.Data {
+		if name, ok := tagNames[key]; ok {
+			rec.Tags[name] = fmt.Sprint(val)
+			continue
+		}
+		switch key {
+		case logrus.ErrorKey:
+			if err, ok := val.(error); ok && !sentry.IsNilError(err) {
+				rec.Err = err
+				continue
+			}
+		case "fingerprint":
+			// see WithFingerprint()
+			if fingerprint, ok := val.([]string); ok {
+				rec.Fingerprint = fingerprint
+				continue
+			}
+		}
+
+		switch val.(type) {
+		case string, bool, int, int64, uint64, float64:
+			rec.Extra[key] = val
+		default:
+			rec.Extra[key] = fmt.Sprint(val)
+		}
+	}
+
+	captureFields(ctx, hook.opts.Client, rec)
+	return nil
+}
+
+// reported levels of logrus hook are captured, and so they are breadcrumbs already
+var logrusBreadcrumbLevels = []logrus.Level{
+	logrus.InfoLevel,
+	logrus.DebugLevel,
+	logrus.TraceLevel,
+}
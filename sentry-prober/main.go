@@ -18,6 +18,7 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/muravjov/slog"
 	"github.com/muravjov/slog/base"
+	"github.com/muravjov/slog/fakesentry"
 	"github.com/muravjov/slog/sentry"
 	"github.com/muravjov/slog/stress"
 	slogV2 "github.com/muravjov/slog/v2"
@@ -197,6 +198,19 @@ func serializedPacket(packetJSON []byte) (io.Reader, string, error) {
 	return bytes.NewReader(packetJSON), "application/json", nil
 }
 
+// serveFakeSentry() serves fake Sentry at dsn host, accepting keys of dsn only
+func serveFakeSentry(dsn string, eventsPath string) {
+	s := fakesentry.NewServer()
+	err := s.AllowDSN(dsn)
+	base.CheckFatal("Bad DSN: %s", err)
+
+	if eventsPath != "" {
+		s.Log = base.OpenLog(eventsPath)
+	}
+
+	stress.ServeDummyHTTPEx(dsn, s.Routes(), "")
+}
+
 func main() {
 	cpuprofile := flag.String("cpuprofile", "", "save CPU profile to file")
 
@@ -205,14 +219,18 @@ func main() {
 	isRandom := flag.BoolP("random", "", false, "add random string to message")
 	isWatcher := flag.BoolP("watcher", "", false, "send message via watcher")
 	transport := flag.StringP("transport", "", "default",
-		"transport to use (not for --watcher): default|raven-go|slog|curl-print|curl-execute")
+		"transport to use (not for --watcher): default|raven-go|envelope|slog|curl-print|curl-execute")
+
+	replaySpool := flag.StringP("replay-spool", "", "", "resend events spooled in the directory and exit")
 
 	isStress := flag.Bool("stress", false, "run stress test")
 	stressRPS := flag.Float64("stress-rps", 5., "stress: request per second; < 0 means requesting without time throttling")
 	stressDuration := flag.Float64("stress-duration", -1., "stress duration; < 0 means stress to be stopped with Ctrl+C")
 	stressReqNumber := flag.Int("stress-request-number", -1,
 		"N >=0 means direct stress mode \"for range N {StartJob()}\"")
-	selfStress := flag.Bool("stress-self", false, "start dummy http server at dsn")
+	selfStress := flag.Bool("stress-self", false, "start fake Sentry server at dsn")
+	fakeServer := flag.Bool("fake-server", false, "serve fake Sentry at dsn until Ctrl+C, events are queried at /api/events/")
+	fakeEvents := flag.StringP("fake-events", "", "", "fake Sentry server: append events to the file as JSON lines")
 	keepaliveStress := flag.Bool("stress-keepalive", false, "reuse TCP connections between different HTTP requests")
 	stressTimeout := flag.Float64("stress-timeout", 5., "stress timeout; = 0 means no request timeout")
 
@@ -235,27 +253,31 @@ func main() {
 		log.Fatalf("DSN argument required")
 	}
 
+	if *replaySpool != "" {
+		slogV2.MustSetDSNAndHandler(dsn)
+
+		// after MustSetDSN() not to start replay in the background
+		err := sentry.SetSpool(sentry.SpoolOptions{Dir: *replaySpool})
+		if err != nil {
+			log.Fatalf("Can't set spool: %s", err)
+		}
+
+		sent, err := sentry.ReplaySpool()
+		fmt.Printf("Events resent: %d\n", sent)
+		if err != nil {
+			log.Fatalf("Replay failed: %s", err)
+		}
+		return
+	}
+
+	if *fakeServer {
+		serveFakeSentry(dsn, *fakeEvents)
+		return
+	}
+
 	if *isStress {
 		if *selfStress {
-			// url(r'^api/(?P<project_id>[\w_-]+)/store/$', api.StoreView.as_view(), name='sentry-api-store')
-			route := stress.NewRoute(`^/api/(?P<project_id>[\w_-]+)/store/$`, func(w http.ResponseWriter, r *http.Request, match stress.Match) {
-				s := match.GetArgumentsMap()["project_id"]
-				_ = s
-				//fmt.Println(s)
-
-				// :TODO: go-raven sends events as "application/octet-stream" and evidently gzip-es them
-				// var dat StrDict
-				// err := DecodeCheckJSONBody(w, r, &dat)
-				// if err != nil {
-				// 	return
-				// }
-				//spew.Dump(dat)
-
-				b := stress.MarshalIndent(map[string]string{})
-				stress.ServeJSON(w, http.StatusCreated, b)
-			})
-
-			go stress.ServeDummyHTTP(dsn, route, "")
+			go serveFakeSentry(dsn, *fakeEvents)
 		}
 
 		//fmt.Println(*stressRPS, *stressDuration)
@@ -271,7 +293,11 @@ func main() {
 			isRandom: *isRandom,
 		}
 
-		client := MakeClient(dsn)
+		protocol := ""
+		if *transport == "envelope" {
+			protocol = sentry.EnvelopeProtocol
+		}
+		client := MakeClient(dsn, protocol)
 
 		jobFunc := func() {
 			eventID, err := PostSentryEvent(event, client, rc)
@@ -288,11 +314,17 @@ func main() {
 
 	slogV2.MustSetDSNAndHandler(dsn)
 
-	ht, ok := raven.DefaultClient.Transport.(*raven.HTTPTransport)
+	ht, ok := raven.DefaultClient.Transport.(*sentry.HTTPTransport)
 	base.Assert(ok)
 
 	switch *transport {
-	case "default", "raven-go":
+	case "default":
+	case "raven-go":
+		raven.DefaultClient.Transport = &raven.HTTPTransport{
+			Client: ht.Client,
+		}
+	case "envelope":
+		ht.Protocol = sentry.EnvelopeProtocol
 	case "slog", "curl-print", "curl-execute":
 		t := &HTTPTransport{
 			Mode: *transport,
@@ -3,10 +3,8 @@ package main
 import (
 	"fmt"
 	"net/http"
-	urlModule "net/url"
 	"path"
 	"runtime"
-	"strings"
 
 	raven "github.com/getsentry/raven-go"
 	"github.com/muravjov/slog/base"
@@ -14,7 +12,6 @@ import (
 	"github.com/muravjov/slog/stress"
 )
 
-// :COPY_N_PASTE: Client
 type Client struct {
 	//Tags map[string]string
 	//context *context
@@ -22,51 +19,24 @@ type Client struct {
 	url        string
 	projectID  string
 	authHeader string
+	protocol   string
 }
 
-func MakeClient(dsn string) *Client {
-	client := &Client{}
-
-	SetDSN := func(dsn string) error {
-		if dsn == "" {
-			return nil
-		}
-
-		uri, err := urlModule.Parse(dsn)
-		if err != nil {
-			return err
-		}
-
-		if uri.User == nil {
-			return raven.ErrMissingUser
-		}
-		publicKey := uri.User.Username()
-		secretKey, hasSecretKey := uri.User.Password()
-		uri.User = nil
-
-		if idx := strings.LastIndex(uri.Path, "/"); idx != -1 {
-			client.projectID = uri.Path[idx+1:]
-			uri.Path = uri.Path[:idx+1] + "api/" + client.projectID + "/store/"
-		}
-		if client.projectID == "" {
-			return raven.ErrMissingProjectID
-		}
-
-		client.url = uri.String()
-
-		if hasSecretKey {
-			client.authHeader = fmt.Sprintf("Sentry sentry_version=4, sentry_key=%s, sentry_secret=%s", publicKey, secretKey)
-		} else {
-			client.authHeader = fmt.Sprintf("Sentry sentry_version=4, sentry_key=%s", publicKey)
-		}
+// protocol - Sentry protocol, if empty then DSN decides
+func MakeClient(dsn string, protocol string) *Client {
+	d, err := sentry.ParseDSN(dsn)
+	base.CheckError(err)
 
-		return nil
+	if protocol == "" {
+		protocol = d.Protocol
 	}
 
-	err := SetDSN(dsn)
-	base.CheckError(err)
-
-	return client
+	return &Client{
+		url:        d.StoreURL,
+		projectID:  d.ProjectID,
+		authHeader: d.AuthHeader,
+		protocol:   protocol,
+	}
 }
 
 type Event struct {
@@ -138,11 +108,37 @@ func PostSentryEvent(ev *Event, client *Client, rc *stress.RequestContext) (even
 		return
 	}
 
-	packetJSON, err := packet.JSON()
+	var req *http.Request
+	if client.protocol == sentry.EnvelopeProtocol {
+		req, err = sentry.NewRequest(client.protocol, url, authHeader, packet)
+	} else {
+		req, err = newStoreRequest(url, authHeader, packet)
+	}
 	if err != nil {
-		err = fmt.Errorf("error marshaling packet %+v to JSON: %v", packet, err)
 		return
 	}
+
+	// res, err := httpClient.Do(req)
+	// if err != nil {
+	// 	return
+	// }
+	// io.Copy(ioutil.Discard, res.Body)
+	// res.Body.Close()
+	// if res.StatusCode != 200 {
+	// 	err = fmt.Errorf("raven: got http status %d", res.StatusCode)
+	// 	return
+	// }
+	stress.ExecuteRequest(req, rc)
+
+	return packet.EventID, nil
+}
+
+// store protocol request, but compressed with our serializedPacket()
+func newStoreRequest(url, authHeader string, packet *raven.Packet) (*http.Request, error) {
+	packetJSON, err := packet.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling packet %+v to JSON: %v", packet, err)
+	}
 	//fmt.Println(string(packetJSON))
 
 	headers := map[string]string{
@@ -153,32 +149,17 @@ func PostSentryEvent(ev *Event, client *Client, rc *stress.RequestContext) (even
 
 	body, contentType, err := serializedPacket(packetJSON)
 	if err != nil {
-		err = fmt.Errorf("error serializing packet: %v", err)
-		return
+		return nil, fmt.Errorf("error serializing packet: %v", err)
 	}
 	headers["Content-Type"] = contentType
 
 	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
-		err = fmt.Errorf("can't create new request: %v", err)
-		return
+		return nil, fmt.Errorf("can't create new request: %v", err)
 	}
 
 	for key, val := range headers {
 		req.Header.Add(key, val)
 	}
-
-	// res, err := httpClient.Do(req)
-	// if err != nil {
-	// 	return
-	// }
-	// io.Copy(ioutil.Discard, res.Body)
-	// res.Body.Close()
-	// if res.StatusCode != 200 {
-	// 	err = fmt.Errorf("raven: got http status %d", res.StatusCode)
-	// 	return
-	// }
-	stress.ExecuteRequest(req, rc)
-
-	return packet.EventID, nil
+	return req, nil
 }
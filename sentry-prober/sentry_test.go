@@ -22,7 +22,7 @@ func TestStress(t *testing.T) {
 		}
 
 		////////////////////////
-		client := MakeClient(dsn)
+		client := MakeClient(dsn, "")
 
 		//httpClient := &http.Client{}
 		rc := stress.NewRequestContext()
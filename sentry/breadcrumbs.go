@@ -0,0 +1,142 @@
+package sentry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// https://develop.sentry.dev/sdk/event-payloads/breadcrumbs/
+type Breadcrumb struct {
+	// unix time in seconds
+	Timestamp float64                `json:"timestamp"`
+	Type      string                 `json:"type,omitempty"`
+	Category  string                 `json:"category,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Level     raven.Severity         `json:"level,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+type Breadcrumbs struct {
+	Values []Breadcrumb `json:"values"`
+}
+
+func (b *Breadcrumbs) Class() string { return "breadcrumbs" }
+
+type BreadcrumbsOptions struct {
+	// ring buffer size, 0 turns breadcrumbs off
+	Size int
+	// less severe records are not kept, raven.DEBUG by default
+	MinLevel raven.Severity
+}
+
+var severityRanks = map[raven.Severity]int{
+	raven.DEBUG:   0,
+	raven.INFO:    1,
+	raven.WARNING: 2,
+	raven.ERROR:   3,
+	raven.FATAL:   4,
+}
+
+// SeverityRank() orders levels from raven.DEBUG, 0, to raven.FATAL
+func SeverityRank(level raven.Severity) int {
+	return severityRanks[level]
+}
+
+type breadcrumbRing struct {
+	mu       sync.Mutex
+	minLevel raven.Severity
+	buf      []Breadcrumb
+	// next slot to write
+	pos  int
+	full bool
+}
+
+// nil means breadcrumbs are off
+var breadcrumbs *breadcrumbRing
+
+// SetBreadcrumbs() turns on collecting recent log records of the process; they
+// are attached to captured events as breadcrumbs
+func SetBreadcrumbs(opts BreadcrumbsOptions) {
+	if opts.Size <= 0 {
+		breadcrumbs = nil
+		return
+	}
+
+	minLevel := opts.MinLevel
+	if minLevel == "" {
+		minLevel = raven.DEBUG
+	}
+	breadcrumbs = &breadcrumbRing{
+		minLevel: minLevel,
+		buf:      make([]Breadcrumb, opts.Size),
+	}
+}
+
+// BreadcrumbsEnabledFor() tells whether log records of the level are kept as breadcrumbs
+func BreadcrumbsEnabledFor(level raven.Severity) bool {
+	b := breadcrumbs
+	return b != nil && severityRanks[level] >= severityRanks[b.minLevel]
+}
+
+// AddBreadcrumb() keeps the record for next captured events;
+// records less severe than BreadcrumbsOptions.MinLevel are ignored
+func AddBreadcrumb(crumb Breadcrumb) {
+	b := breadcrumbs
+	if b == nil || !BreadcrumbsEnabledFor(crumb.Level) {
+		return
+	}
+
+	if crumb.Timestamp == 0 {
+		crumb.Timestamp = UnixTime(time.Now())
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf[b.pos] = crumb
+	b.pos++
+	if b.pos == len(b.buf) {
+		b.pos = 0
+		b.full = true
+	}
+}
+
+// oldest first
+func (b *breadcrumbRing) snapshot() []Breadcrumb {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var res []Breadcrumb
+	if b.full {
+		res = append(res, b.buf[b.pos:]...)
+	}
+	return append(res, b.buf[:b.pos]...)
+}
+
+// Breadcrumb.Timestamp
+func UnixTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// attachBreadcrumbs() adds recent records to the packet, and then the packet itself
+// becomes a breadcrumb for next events
+func attachBreadcrumbs(packet *raven.Packet) {
+	b := breadcrumbs
+	if b == nil {
+		return
+	}
+
+	if values := b.snapshot(); len(values) > 0 {
+		packet.Interfaces = append(packet.Interfaces, &Breadcrumbs{
+			Values: values,
+		})
+	}
+
+	AddBreadcrumb(Breadcrumb{
+		Category: "sentry.event",
+		Message:  packet.Message,
+		Level:    packet.Level,
+	})
+}
@@ -0,0 +1,105 @@
+package sentry
+
+import (
+	"path"
+	"runtime"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Packets of loggers, which know the call site by program counter rather than
+// by calldepth, e.g. log/slog.Record.PC
+
+// NewStacktraceAt() makes stacktrace, which starts at pc of the current goroutine,
+// like raven.NewStacktrace(); if pc is not on the stack, e.g. the record is
+// handled asynchronously, the stacktrace is the call site only
+func NewStacktraceAt(pc uintptr, context int) *raven.Stacktrace {
+	if pc == 0 {
+		return nil
+	}
+
+	callers := make([]uintptr, 100)
+	callers = callers[:runtime.Callers(1, callers)]
+
+	pcs := []uintptr{pc}
+	for i, caller := range callers {
+		if caller == pc {
+			pcs = callers[i:]
+			break
+		}
+	}
+
+	var includePaths []string
+	if client := raven.DefaultClient; client != nil {
+		includePaths = client.IncludePaths()
+	}
+
+	// :COPY_N_PASTE: raven.NewStacktrace()
+	var frames []*raven.StacktraceFrame
+	callersFrames := runtime.CallersFrames(pcs)
+	for {
+		fr, more := callersFrames.Next()
+		if fr.Func != nil || fr.Function != "" {
+			frame := raven.NewStacktraceFrame(fr.PC, fr.Function, fr.File, fr.Line, context, includePaths)
+			if frame != nil {
+				frames = append(frames, frame)
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// Sentry wants the frames with the oldest first
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return &raven.Stacktrace{Frames: frames}
+}
+
+// NewErrorPacketAt() = NewErrorPacket() with call site pc
+func NewErrorPacketAt(message string, tags map[string]string, pc uintptr, level raven.Severity, grouping Grouping) *raven.Packet {
+	// :TRICKY: no nil *raven.Stacktrace in the interface, packet.Init() calls Culprit() of it;
+	// pc is 0, e.g. for log/slog records without source
+	packet := raven.NewPacket(message)
+	if stacktrace := NewStacktraceAt(pc, 3); stacktrace != nil {
+		packet.Interfaces = append(packet.Interfaces, stacktrace)
+	}
+	packet.Level = level
+	packet.Fingerprint = grouping.FingerprintAt(message, tags, pc)
+
+	return packet
+}
+
+// NewExceptionPacketAt() = NewExceptionPacket() with call site pc
+func NewExceptionPacketAt(err error, message string, tags map[string]string, pc uintptr, level raven.Severity, grouping Grouping) *raven.Packet {
+	packet := newExceptionPacket(err, message, NewStacktraceAt(pc, 3), level)
+	packet.Fingerprint = grouping.FingerprintAt(message, tags, pc)
+
+	return packet
+}
+
+// NewMessagePacketAt() = NewMessagePacket() with call site pc
+func NewMessagePacketAt(message string, tags map[string]string, pc uintptr, iObject *raven.Message, grouping Grouping) *raven.Packet {
+	packet := Interface2Packet(message, iObject, raven.WARNING)
+
+	if pc != 0 {
+		fr, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if fr.File != "" {
+			extra := packet.Extra
+			extra["filename"] = path.Base(fr.File)
+			extra["lineno"] = fr.Line
+			extra["pathname"] = fr.File
+		}
+	}
+
+	if grouping.Format == "" {
+		grouping.Format = iObject.Message
+	}
+	packet.Fingerprint = grouping.FingerprintAt(message, tags, pc)
+
+	return packet
+}
@@ -0,0 +1,160 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// What to do if DSN check fails
+type CheckPolicy int
+
+const (
+	// no check
+	CheckOff CheckPolicy = iota
+	// log the failure and go on
+	CheckWarn
+	// log.Fatalf()
+	CheckFatal
+)
+
+// ParseCheckPolicy() parses "off", "warn" or "fatal"
+func ParseCheckPolicy(s string) (CheckPolicy, error) {
+	switch s {
+	case "off", "":
+		return CheckOff, nil
+	case "warn":
+		return CheckWarn, nil
+	case "fatal":
+		return CheckFatal, nil
+	}
+	return CheckOff, fmt.Errorf("unknown Sentry check policy: %s", s)
+}
+
+var defaultCheckTimeout = time.Second * 5
+
+type CheckOptions struct {
+	Policy CheckPolicy
+	// 5 seconds if zero
+	Timeout time.Duration
+	// send a DEBUG test event instead of an empty envelope; for old Sentry
+	// servers without envelope endpoint
+	Event bool
+}
+
+var checkOptions CheckOptions
+
+// SetCheck() makes MustSetDSN() check that Sentry accepts the DSN, see Client.Check();
+// MustSetDSN() waits for the check up to the timeout
+func SetCheck(opts CheckOptions) {
+	checkOptions = opts
+}
+
+func checkDSN(dsn string) {
+	opts := checkOptions
+	if opts.Policy == CheckOff {
+		return
+	}
+
+	err := DefaultClient().Check(opts.Timeout, opts.Event)
+	if err == nil {
+		return
+	}
+	if opts.Policy == CheckFatal {
+		log.Fatalf("Sentry DSN '%s' check failed: %s", dsn, err)
+	}
+	log.Printf("Sentry DSN '%s' check failed: %s", dsn, err)
+}
+
+// Check() = Client.Check() of the global DSN
+func Check(timeout time.Duration, event bool) error {
+	return DefaultClient().Check(timeout, event)
+}
+
+// Check() makes sure Sentry is reachable and accepts the key and the project:
+// it sends an empty envelope, which Sentry authenticates but ignores, or
+// a DEBUG test event if event is true
+func (c *Client) Check(timeout time.Duration, event bool) error {
+	if c == nil || c.Raven == nil || c.DSN == nil || c.DSN.StoreURL == "" {
+		return fmt.Errorf("no Sentry DSN")
+	}
+	if timeout == 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	packet := raven.NewPacket("Sentry DSN check")
+	packet.Level = raven.DEBUG
+	if err := packet.Init(c.DSN.ProjectID); err != nil {
+		return err
+	}
+
+	t, ok := c.Raven.Transport.(*HTTPTransport)
+	if !ok {
+		// registered transports, e.g. in-memory ones, get the test event
+		return checkTransport(c.Raven.Transport, c.DSN, packet, timeout)
+	}
+
+	var req *http.Request
+	var err error
+	if event {
+		req, err = NewRequest(t.Protocol, c.DSN.StoreURL, c.DSN.AuthHeader, packet)
+	} else {
+		req, err = http.NewRequest("POST", EnvelopeURL(c.DSN.StoreURL), bytes.NewReader([]byte("{}\n")))
+		if err == nil {
+			req.Header.Set("X-Sentry-Auth", envelopeAuthHeader(c.DSN.AuthHeader))
+			req.Header.Set("User-Agent", userAgent)
+			req.Header.Set("Content-Type", "application/x-sentry-envelope")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	httpClient := t.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusTooManyRequests:
+		// the key is valid, Sentry just holds us back
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("Sentry rejected the key: http status %d - x-sentry-error: %s", res.StatusCode, res.Header.Get("X-Sentry-Error"))
+	case http.StatusNotFound:
+		return fmt.Errorf("no such Sentry project or endpoint: %s", req.URL)
+	}
+	return fmt.Errorf("http status %d - x-sentry-error: %s", res.StatusCode, res.Header.Get("X-Sentry-Error"))
+}
+
+func checkTransport(t raven.Transport, d *DSN, packet *raven.Packet, timeout time.Duration) error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- t.Send(d.StoreURL, d.AuthHeader, packet)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timeout %s", timeout)
+	}
+}
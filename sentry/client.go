@@ -0,0 +1,177 @@
+package sentry
+
+import (
+	"context"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Client sends events to its own Sentry project, so that a process is able to report
+// different subsystems to different projects. Package functions like CaptureErrorAndWait()
+// use DefaultClient()
+type Client struct {
+	Raven *raven.Client
+	DSN   *DSN
+	// added to every event; explicit capture tags win
+	Tags map[string]string
+	// nil means SentryErrorHandler
+	ErrorHandler SentryErrorHandlerType
+}
+
+// NewClient() makes client with its own DSN and transport; seh may be nil,
+// see Client.ErrorHandler
+func NewClient(dsn string, tags map[string]string, seh SentryErrorHandlerType) (*Client, error) {
+	d, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := raven.New(d.Raven)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := makeTransport(d, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ht, ok := transport.(*HTTPTransport); ok {
+		ht.ErrorHandler = seh
+	}
+	rc.Transport = transport
+
+	return &Client{
+		Raven:        rc,
+		DSN:          d,
+		Tags:         tags,
+		ErrorHandler: seh,
+	}, nil
+}
+
+// DefaultClient() returns client of raven.DefaultClient, see MustSetDSN(); nil if there is no one
+func DefaultClient() *Client {
+	rc := raven.DefaultClient
+	if rc == nil {
+		return nil
+	}
+	return &Client{
+		Raven: rc,
+		DSN:   currentDSN,
+	}
+}
+
+func (c *Client) handleError(err error) {
+	seh := c.ErrorHandler
+	if seh == nil {
+		seh = SentryErrorHandler
+	}
+	if seh != nil {
+		seh(err)
+	}
+}
+
+func (c *Client) withTags(tags map[string]string) map[string]string {
+	if len(c.Tags) == 0 {
+		return tags
+	}
+
+	res := map[string]string{}
+	for k, v := range c.Tags {
+		res[k] = v
+	}
+	for k, v := range tags {
+		res[k] = v
+	}
+	return res
+}
+
+// CaptureAndWait() = sentry.CaptureAndWait() via the client
+func (c *Client) CaptureAndWait(packet *raven.Packet, tags map[string]string) string {
+	if c == nil || c.Raven == nil {
+		return ""
+	}
+	tags = c.withTags(tags)
+	countCaptured(packet.Level, tags["module"])
+
+	if !sample(packet, tags) {
+		countDropped(packet.Level, tags["module"], DropSampled)
+		return ""
+	}
+
+	stampPacket(packet)
+	classifyPacketFrames(packet)
+	attachBreadcrumbs(packet)
+
+	// :TRICKY: processors see capture tags as packet.Tags
+	packet.AddTags(tags)
+	tags = nil
+	if !process(packet) {
+		countDropped(packet.Level, packetModule(packet), DropFiltered)
+		return ""
+	}
+
+	if q := queue; q != nil && packet.Level != raven.FATAL {
+		if packet.EventID == "" {
+			packet.EventID = newEventID()
+		}
+		if time.Time(packet.Timestamp).IsZero() {
+			packet.Timestamp = raven.Timestamp(time.Now())
+		}
+
+		q.push(&queuedEvent{
+			client: c,
+			packet: packet,
+			tags:   tags,
+		})
+		return packet.EventID
+	}
+
+	return captureAndWait(c, packet, tags)
+}
+
+// CaptureAndWaitCtx() = CaptureAndWait() with the scope of ctx
+func (c *Client) CaptureAndWaitCtx(ctx context.Context, packet *raven.Packet, tags map[string]string) string {
+	tags = ScopeFromContext(ctx).apply(packet, tags)
+	return c.CaptureAndWait(packet, tags)
+}
+
+// CaptureErrorAndWait() = sentry.CaptureErrorAndWait() via the client
+func (c *Client) CaptureErrorAndWait(message string, tags map[string]string, calldepth int, level raven.Severity) string {
+	return c.CaptureAndWait(NewErrorPacket(message, tags, calldepth+1, level, Grouping{}), tags)
+}
+
+// CaptureErrorAndWaitEx() = CaptureErrorAndWait() with grouping control
+func (c *Client) CaptureErrorAndWaitEx(message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) string {
+	return c.CaptureAndWait(NewErrorPacket(message, tags, calldepth+1, level, grouping), tags)
+}
+
+// CaptureErrorAndWaitCtx() = CaptureErrorAndWait() with the scope of ctx
+func (c *Client) CaptureErrorAndWaitCtx(ctx context.Context, message string, tags map[string]string, calldepth int, level raven.Severity) string {
+	return c.CaptureAndWaitCtx(ctx, NewErrorPacket(message, tags, calldepth+1, level, Grouping{}), tags)
+}
+
+// CaptureExceptionAndWait() = sentry.CaptureExceptionAndWait() via the client
+func (c *Client) CaptureExceptionAndWait(err error, message string, tags map[string]string, calldepth int, level raven.Severity) string {
+	return c.CaptureAndWait(NewExceptionPacket(err, message, tags, calldepth+1, level, Grouping{}), tags)
+}
+
+// CaptureExceptionAndWaitEx() = CaptureExceptionAndWait() with grouping control
+func (c *Client) CaptureExceptionAndWaitEx(err error, message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) string {
+	return c.CaptureAndWait(NewExceptionPacket(err, message, tags, calldepth+1, level, grouping), tags)
+}
+
+// CaptureMessageAndWait() = sentry.CaptureMessageAndWait() via the client
+func (c *Client) CaptureMessageAndWait(message string, tags map[string]string, calldepth int, iObject *raven.Message) string {
+	return c.CaptureAndWait(NewMessagePacket(message, tags, calldepth+1, iObject, Grouping{}), tags)
+}
+
+// CaptureMessageAndWaitEx() = CaptureMessageAndWait() with grouping control
+func (c *Client) CaptureMessageAndWaitEx(message string, tags map[string]string, calldepth int, iObject *raven.Message, grouping Grouping) string {
+	return c.CaptureAndWait(NewMessagePacket(message, tags, calldepth+1, iObject, grouping), tags)
+}
+
+// CaptureMessageAndWaitCtx() = CaptureMessageAndWait() with the scope of ctx
+func (c *Client) CaptureMessageAndWaitCtx(ctx context.Context, message string, tags map[string]string, calldepth int, iObject *raven.Message) string {
+	return c.CaptureAndWaitCtx(ctx, NewMessagePacket(message, tags, calldepth+1, iObject, Grouping{}), tags)
+}
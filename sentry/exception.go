@@ -0,0 +1,131 @@
+package sentry
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+
+	"github.com/getsentry/raven-go"
+	pkgErrors "github.com/pkg/errors"
+)
+
+// errors of github.com/pkg/errors know where they are made
+type stackTracer interface {
+	StackTrace() pkgErrors.StackTrace
+}
+
+// like logrus_sentry.SentryHook.convertStackTrace()
+func convertStackTrace(st pkgErrors.StackTrace, includePaths []string) *raven.Stacktrace {
+	var frames []*raven.StacktraceFrame
+	for _, f := range st {
+		// pkg/errors keeps return addresses
+		pc := uintptr(f) - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
+		file, line := fn.FileLine(pc)
+		frame := raven.NewStacktraceFrame(pc, fn.Name(), file, line, 3, includePaths)
+		if frame != nil {
+			frames = append(frames, frame)
+		}
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// Sentry wants the oldest frame first
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return &raven.Stacktrace{Frames: frames}
+}
+
+// IsNilError() tells err is nil or a typed nil, e.g. (*MyErr)(nil): Error() of it
+// is likely to panic, so it's not reported as exception
+func IsNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// NewExceptions() makes Sentry exceptions of err and its errors.Unwrap() chain;
+// stacktraces of github.com/pkg/errors are used as exception frames.
+// stacktrace goes to the outermost error, if it has no own one
+func NewExceptions(err error, stacktrace *raven.Stacktrace) *raven.Exceptions {
+	var includePaths []string
+	if client := raven.DefaultClient; client != nil {
+		includePaths = client.IncludePaths()
+	}
+
+	var chain []*raven.Exception
+	var st *raven.Stacktrace
+	for e := err; !IsNilError(e); e = errors.Unwrap(e) {
+		if tracer, ok := e.(stackTracer); ok {
+			st = convertStackTrace(tracer.StackTrace(), includePaths)
+		}
+
+		// :TRICKY: pkg/errors.Wrap() makes 2 errors with the same text: with stack and with message;
+		// report them as one
+		next := errors.Unwrap(e)
+		if !IsNilError(next) && next.Error() == e.Error() {
+			continue
+		}
+
+		// :TRICKY: not raven.NewException(), it takes "db: timeout" as module "db"
+		chain = append(chain, &raven.Exception{
+			Type:       reflect.TypeOf(e).String(),
+			Value:      e.Error(),
+			Stacktrace: st,
+		})
+		st = nil
+	}
+
+	if len(chain) == 0 {
+		return nil
+	}
+	if chain[0].Stacktrace == nil {
+		chain[0].Stacktrace = stacktrace
+	}
+
+	// Sentry wants the outermost exception last
+	exceptions := &raven.Exceptions{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		exceptions.Values = append(exceptions.Values, chain[i])
+	}
+	return exceptions
+}
+
+// NewExceptionPacket() makes packet with exceptions of err for CaptureAndWait();
+// message is what is logged, e.g. "query failed: <err>"
+func NewExceptionPacket(err error, message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) *raven.Packet {
+	var includePaths []string
+	if client := raven.DefaultClient; client != nil {
+		includePaths = client.IncludePaths()
+	}
+
+	stacktrace := raven.NewStacktrace(calldepth, 3, includePaths)
+	packet := newExceptionPacket(err, message, stacktrace, level)
+	packet.Fingerprint = grouping.fingerprint(message, tags, calldepth)
+
+	return packet
+}
+
+func newExceptionPacket(err error, message string, stacktrace *raven.Stacktrace, level raven.Severity) *raven.Packet {
+	packet := raven.NewPacket(message)
+	packet.Level = level
+
+	// :TRICKY: no nil pointers in packet.Interfaces, packet.Init() calls Culprit() of them
+	if exceptions := NewExceptions(err, stacktrace); exceptions != nil {
+		packet.Interfaces = append(packet.Interfaces, exceptions)
+	} else if stacktrace != nil {
+		packet.Interfaces = append(packet.Interfaces, stacktrace)
+	}
+	return packet
+}
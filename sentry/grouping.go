@@ -0,0 +1,93 @@
+package sentry
+
+import (
+	"runtime"
+)
+
+// Grouping controls how Sentry aggregates the captured event
+type Grouping struct {
+	// message template, e.g. format string of logger.Errorf();
+	// message itself is used if empty
+	Format string
+	// explicit fingerprint, overrides grouping strategy; see
+	// https://docs.sentry.io/product/data-management-settings/event-grouping/fingerprint-rules/
+	Fingerprint []string
+}
+
+// What grouping strategy knows about the event
+type GroupingInfo struct {
+	Message string
+	Format  string
+	// go-logging module, "module" tag
+	Module string
+	// call site, e.g. "github.com/muravjov/slog/sentry.TestGrouping"
+	Function string
+}
+
+// GroupingStrategy makes fingerprint for the event;
+// nil fingerprint means Sentry default grouping: by stacktrace for errors and
+// by message template for warnings
+type GroupingStrategy func(info GroupingInfo) []string
+
+// Grouping by message template; unlike stacktrace grouping, it's not broken
+// by source edits
+func GroupByFormat(info GroupingInfo) []string {
+	return []string{info.Format}
+}
+
+// Grouping by go-logging module and message template
+func GroupByModuleAndFormat(info GroupingInfo) []string {
+	return []string{info.Module, info.Format}
+}
+
+// Grouping by function, where the event is logged from
+func GroupByCallSite(info GroupingInfo) []string {
+	if info.Function == "" {
+		return nil
+	}
+	return []string{info.Function}
+}
+
+var groupingStrategy GroupingStrategy = nil
+
+// Install grouping strategy for CaptureErrorAndWait(), CaptureMessageAndWait() and friends
+func SetGroupingStrategy(gs GroupingStrategy) {
+	groupingStrategy = gs
+}
+
+// calldepth is like for runtime.Caller()
+func (g Grouping) fingerprint(message string, tags map[string]string, calldepth int) []string {
+	var pc uintptr
+	if g.Fingerprint == nil && groupingStrategy != nil {
+		pc, _, _, _ = runtime.Caller(calldepth + 1)
+	}
+	return g.FingerprintAt(message, tags, pc)
+}
+
+// FingerprintAt() returns fingerprint for the event logged at pc, see runtime.Callers()
+func (g Grouping) FingerprintAt(message string, tags map[string]string, pc uintptr) []string {
+	if g.Fingerprint != nil {
+		return g.Fingerprint
+	}
+
+	gs := groupingStrategy
+	if gs == nil {
+		return nil
+	}
+
+	info := GroupingInfo{
+		Message: message,
+		Format:  g.Format,
+		Module:  tags["module"],
+	}
+	if info.Format == "" {
+		info.Format = message
+	}
+	if pc != 0 {
+		if f := runtime.FuncForPC(pc); f != nil {
+			info.Function = f.Name()
+		}
+	}
+
+	return gs(info)
+}
@@ -0,0 +1,123 @@
+package sentry
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Which stacktrace frames are application code; Sentry UI collapses the others
+type InAppOptions struct {
+	// path of the main module, detected from build info if empty
+	MainModule string
+	// package path prefixes of application code, in addition to the main module
+	Include []string
+	// package path prefixes, which are not application code even if included,
+	// e.g. a library within the main module; win over Include
+	Exclude []string
+}
+
+var inApp InAppOptions
+
+var mainModule string
+var mainModuleOnce sync.Once
+
+// SetInApp() configures in-app frame detection; frames of the main module, see
+// debug.ReadBuildInfo(), and of package main are in-app by default
+func SetInApp(opts InAppOptions) {
+	inApp = opts
+}
+
+// GetInApp() returns options set by SetInApp()
+func GetInApp() InAppOptions {
+	return inApp
+}
+
+func hasPathPrefix(pkg, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" || !strings.HasPrefix(pkg, prefix) {
+		return false
+	}
+	rest := pkg[len(prefix):]
+	// raven's frame module may end with receiver, like "pkg.(*T)"
+	return rest == "" || rest[0] == '/' || rest[0] == '.'
+}
+
+// IsInApp() tells whether the package, e.g. "github.com/me/app/db", is application code
+func IsInApp(pkg string) bool {
+	opts := inApp
+	for _, prefix := range opts.Exclude {
+		if hasPathPrefix(pkg, prefix) {
+			return false
+		}
+	}
+	if strings.Contains(pkg, "/vendor/") {
+		return false
+	}
+
+	if hasPathPrefix(pkg, "main") {
+		return true
+	}
+
+	main := opts.MainModule
+	if main == "" {
+		mainModuleOnce.Do(func() {
+			if info, ok := debug.ReadBuildInfo(); ok {
+				mainModule = info.Main.Path
+			}
+		})
+		main = mainModule
+	}
+	if hasPathPrefix(pkg, main) {
+		return true
+	}
+
+	for _, prefix := range opts.Include {
+		if hasPathPrefix(pkg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PackagePath() returns package path of fully qualified function name,
+// e.g. "github.com/me/app/db" of "github.com/me/app/db.(*Conn).Query"
+func PackagePath(funcName string) string {
+	slash := strings.LastIndex(funcName, "/")
+	dot := strings.Index(funcName[slash+1:], ".")
+	if dot == -1 {
+		return funcName
+	}
+	return funcName[:slash+1+dot]
+}
+
+// ClassifyFrames() marks application frames of the stacktrace as in-app
+func ClassifyFrames(stacktrace *raven.Stacktrace) {
+	if stacktrace == nil {
+		return
+	}
+	for _, frame := range stacktrace.Frames {
+		frame.InApp = IsInApp(frame.Module)
+	}
+}
+
+func classifyPacketFrames(packet *raven.Packet) {
+	for _, iface := range packet.Interfaces {
+		switch v := iface.(type) {
+		case *raven.Stacktrace:
+			ClassifyFrames(v)
+		case *raven.Exception:
+			ClassifyFrames(v.Stacktrace)
+		case *raven.Exceptions:
+			for _, exc := range v.Values {
+				ClassifyFrames(exc.Stacktrace)
+			}
+			// like raven.Exception.Culprit(), raven.Exceptions has no one; in-app frames are known now
+			if packet.Culprit == "" && len(v.Values) != 0 {
+				packet.Culprit = v.Values[len(v.Values)-1].Culprit()
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package sentry
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Reasons of events not sent, "reason" label of dropped counter
+const (
+	// queue is full, see StartQueue()
+	DropQueue = "queue"
+	// see SetSampling()
+	DropSampled = "sampled"
+	// by processors, see AddProcessor()
+	DropFiltered = "filtered"
+	// see RateLimitedEvents()
+	DropRateLimited = "rate_limited"
+)
+
+// upper bounds of delivery latency histogram buckets, in seconds
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type Histogram struct {
+	// upper bounds
+	Buckets []float64
+	// Counts[i] is the number of observations <= Buckets[i], not cumulative;
+	// the last one is for observations above all bounds
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+func (h *Histogram) observe(v float64) {
+	if h.Counts == nil {
+		h.Buckets = LatencyBuckets
+		h.Counts = make([]uint64, len(h.Buckets)+1)
+	}
+	i := sort.SearchFloat64s(h.Buckets, v)
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+// Metrics of Sentry pipeline for events of the level and go-logging module
+type Metrics struct {
+	Level  raven.Severity
+	Module string
+
+	Captured uint64
+	Sent     uint64
+	Failed   uint64
+	// reason => count
+	Dropped map[string]uint64
+	// sent bytes, compressed if so
+	Bytes uint64
+	// delivery duration of sent events, seconds
+	Latency Histogram
+}
+
+type metricsKey struct {
+	level  raven.Severity
+	module string
+}
+
+var metricsMutex sync.Mutex
+var metrics = map[metricsKey]*Metrics{}
+
+func packetModule(packet *raven.Packet) string {
+	for _, tag := range packet.Tags {
+		if tag.Key == "module" {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+func updateMetrics(level raven.Severity, module string, update func(m *Metrics)) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	key := metricsKey{level, module}
+	m, ok := metrics[key]
+	if !ok {
+		m = &Metrics{
+			Level:   level,
+			Module:  module,
+			Dropped: map[string]uint64{},
+		}
+		metrics[key] = m
+	}
+	update(m)
+}
+
+func countCaptured(level raven.Severity, module string) {
+	updateMetrics(level, module, func(m *Metrics) { m.Captured++ })
+}
+
+func countDropped(level raven.Severity, module string, reason string) {
+	updateMetrics(level, module, func(m *Metrics) { m.Dropped[reason]++ })
+}
+
+// countDelivery() counts the result of sending packet
+func countDelivery(packet *raven.Packet, err error, duration time.Duration) {
+	updateMetrics(packet.Level, packetModule(packet), func(m *Metrics) {
+		switch err {
+		case nil:
+			m.Sent++
+			m.Latency.observe(duration.Seconds())
+		case ErrRateLimited:
+			m.Dropped[DropRateLimited]++
+		default:
+			m.Failed++
+		}
+	})
+}
+
+func countBytes(packet *raven.Packet, n int64) {
+	if n <= 0 {
+		return
+	}
+	updateMetrics(packet.Level, packetModule(packet), func(m *Metrics) { m.Bytes += uint64(n) })
+}
+
+// GetMetrics() returns copy of metrics, sorted by level and module
+func GetMetrics() []Metrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	var res []Metrics
+	for _, m := range metrics {
+		c := *m
+		c.Dropped = map[string]uint64{}
+		for reason, n := range m.Dropped {
+			c.Dropped[reason] = n
+		}
+		c.Latency.Counts = append([]uint64(nil), m.Latency.Counts...)
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Level != res[j].Level {
+			return res[i].Level < res[j].Level
+		}
+		return res[i].Module < res[j].Module
+	})
+	return res
+}
+
+var publishOnce sync.Once
+
+// PublishExpvar() exposes metrics as expvar "sentry", e.g. at /debug/vars
+func PublishExpvar() {
+	publishOnce.Do(func() {
+		expvar.Publish("sentry", expvar.Func(func() interface{} {
+			return GetMetrics()
+		}))
+	})
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+// WriteMetrics() writes metrics in Prometheus text format
+func WriteMetrics(w io.Writer) {
+	all := GetMetrics()
+
+	labels := func(m *Metrics) string {
+		return fmt.Sprintf(`level="%s",module="%s"`, escapeLabel(string(m.Level)), escapeLabel(m.Module))
+	}
+	header := func(name, typ, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	counter := func(name, help string, value func(m *Metrics) uint64) {
+		header(name, "counter", help)
+		for i := range all {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, labels(&all[i]), value(&all[i]))
+		}
+	}
+
+	counter("slog_sentry_events_captured_total", "Events captured.", func(m *Metrics) uint64 { return m.Captured })
+	counter("slog_sentry_events_sent_total", "Events delivered to Sentry.", func(m *Metrics) uint64 { return m.Sent })
+	counter("slog_sentry_events_failed_total", "Events failed to be delivered.", func(m *Metrics) uint64 { return m.Failed })
+	counter("slog_sentry_sent_bytes_total", "Bytes of delivered requests.", func(m *Metrics) uint64 { return m.Bytes })
+
+	name := "slog_sentry_events_dropped_total"
+	header(name, "counter", "Events not sent on purpose, by reason.")
+	for i := range all {
+		m := &all[i]
+		var reasons []string
+		for reason := range m.Dropped {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(w, "%s{%s,reason=\"%s\"} %d\n", name, labels(m), escapeLabel(reason), m.Dropped[reason])
+		}
+	}
+
+	name = "slog_sentry_delivery_seconds"
+	header(name, "histogram", "Delivery duration of sent events.")
+	for i := range all {
+		m := &all[i]
+		h := &m.Latency
+		if h.Count == 0 {
+			continue
+		}
+		var cumulative uint64
+		for j, bound := range h.Buckets {
+			cumulative += h.Counts[j]
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels(m), formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels(m), h.Count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels(m), formatFloat(h.Sum))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels(m), h.Count)
+	}
+}
+
+// MetricsHandler() serves metrics in Prometheus text format, e.g. at /metrics
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteMetrics(w)
+	})
+}
@@ -0,0 +1,192 @@
+package sentry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// What to do with a new event if the queue is full
+type DropPolicy int
+
+const (
+	// discard the event being captured
+	DropNewest DropPolicy = iota
+	// discard the oldest queued event to free a slot for the new one
+	DropOldest
+	// wait for a free slot; the logging goroutine is blocked only until
+	// the event is queued, not until it is delivered
+	Block
+)
+
+type QueueOptions struct {
+	// queue capacity, raven.MaxQueueBuffer by default
+	Size int
+	// number of background goroutines doing delivery, 1 by default
+	Workers int
+	Policy  DropPolicy
+}
+
+type queuedEvent struct {
+	client *Client
+	packet *raven.Packet
+	tags   map[string]string
+}
+
+type eventQueue struct {
+	ch     chan *queuedEvent
+	policy DropPolicy
+
+	mu      sync.Mutex
+	pending int
+	waiters []chan struct{}
+}
+
+// nil means synchronous mode: CaptureAndWait() waits for delivery
+var queue *eventQueue
+
+// serializes StartQueue() calls
+var queueMutex sync.Mutex
+
+var ErrQueueStarted = errors.New("sentry: queue is already started")
+
+var droppedEvents uint64
+
+// StartQueue() turns on asynchronous mode: CaptureAndWait() and friends
+// put events into a bounded in-memory queue and return immediately,
+// background workers deliver them to Sentry.
+// Call it once, before logging starts: later calls return ErrQueueStarted and
+// keep the running queue, not to lose events queued in it.
+func StartQueue(opts QueueOptions) error {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	if queue != nil {
+		return ErrQueueStarted
+	}
+
+	size := opts.Size
+	if size <= 0 {
+		size = raven.MaxQueueBuffer
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	q := &eventQueue{
+		ch:     make(chan *queuedEvent, size),
+		policy: opts.Policy,
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+
+	queue = q
+	return nil
+}
+
+// Flush() waits until all queued events are delivered, but not longer than timeout.
+// Returns false if timeout hit. Call it before exit, because queued events are lost otherwise.
+func Flush(timeout time.Duration) bool {
+	q := queue
+	if q == nil {
+		return true
+	}
+
+	q.mu.Lock()
+	if q.pending == 0 {
+		q.mu.Unlock()
+		return true
+	}
+	idle := make(chan struct{})
+	q.waiters = append(q.waiters, idle)
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// DroppedEvents() returns how many events were discarded because the queue was full
+func DroppedEvents() uint64 {
+	return atomic.LoadUint64(&droppedEvents)
+}
+
+func (q *eventQueue) worker() {
+	for ev := range q.ch {
+		captureAndWait(ev.client, ev.packet, ev.tags)
+		q.done()
+	}
+}
+
+func (q *eventQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending--
+	if q.pending == 0 {
+		for _, idle := range q.waiters {
+			close(idle)
+		}
+		q.waiters = nil
+	}
+}
+
+func (q *eventQueue) drop(ev *queuedEvent) {
+	atomic.AddUint64(&droppedEvents, 1)
+	countDropped(ev.packet.Level, packetModule(ev.packet), DropQueue)
+	q.done()
+}
+
+func (q *eventQueue) push(ev *queuedEvent) {
+	q.mu.Lock()
+	q.pending++
+	q.mu.Unlock()
+
+	switch q.policy {
+	case Block:
+		q.ch <- ev
+	case DropOldest:
+		for {
+			select {
+			case q.ch <- ev:
+				return
+			default:
+			}
+
+			select {
+			case old := <-q.ch:
+				q.drop(old)
+			default:
+			}
+		}
+	default:
+		select {
+		case q.ch <- ev:
+		default:
+			q.drop(ev)
+		}
+	}
+}
+
+// :TRICKY: raven makes event_id in Packet.Init(), but we need it before
+// the packet gets to raven.Client.Capture()
+func newEventID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	id[6] &= 0x0F // clear version
+	id[6] |= 0x40 // set version to 4 (random uuid)
+	id[8] &= 0x3F // clear variant
+	id[8] |= 0x80 // set to IETF variant
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,156 @@
+package sentry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Send() returns it if the category is held back by Sentry rate limits
+var ErrRateLimited = errors.New("sentry: rate limited")
+
+// Sentry data category for all our packets, see
+// https://develop.sentry.dev/sdk/rate-limiting/#definitions
+const eventCategory = "error"
+
+// if 429 came without any hint how long to wait
+const defaultRetryAfter = time.Second * 60
+
+var rateLimitedEvents uint64
+
+// RateLimitedEvents() returns how many events were not sent because of rate limits
+func RateLimitedEvents() uint64 {
+	return atomic.LoadUint64(&rateLimitedEvents)
+}
+
+type rateLimits struct {
+	mu sync.Mutex
+	// category => deadline, "" category means all
+	until map[string]time.Time
+	// category => number of suppressed events while held back
+	suppressed map[string]int
+}
+
+// parseRateLimits() parses X-Sentry-Rate-Limits header like
+// "60:error;transaction:organization, 2700:default:project"
+func parseRateLimits(header string, now time.Time) map[string]time.Time {
+	res := map[string]time.Time{}
+	for _, limit := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(limit), ":")
+		if len(fields) == 0 || fields[0] == "" {
+			continue
+		}
+
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		deadline := now.Add(time.Duration(seconds * float64(time.Second)))
+
+		categories := []string{""}
+		if len(fields) > 1 && fields[1] != "" {
+			categories = strings.Split(fields[1], ";")
+		}
+		for _, category := range categories {
+			if deadline.After(res[category]) {
+				res[category] = deadline
+			}
+		}
+	}
+	return res
+}
+
+// parseRetryAfter() parses Retry-After header: seconds or HTTP date
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if seconds, err := strconv.ParseFloat(header, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return date.Sub(now)
+	}
+	return defaultRetryAfter
+}
+
+// update() remembers limits from the Sentry response
+func (rl *rateLimits) update(res *http.Response, now time.Time) {
+	var limits map[string]time.Time
+	if header := res.Header.Get("X-Sentry-Rate-Limits"); header != "" {
+		limits = parseRateLimits(header, now)
+	} else if res.StatusCode == http.StatusTooManyRequests {
+		limits = map[string]time.Time{
+			"": now.Add(parseRetryAfter(res.Header.Get("Retry-After"), now)),
+		}
+	}
+	if len(limits) == 0 {
+		return
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.until == nil {
+		rl.until = map[string]time.Time{}
+	}
+	for category, deadline := range limits {
+		if deadline.After(rl.until[category]) {
+			rl.until[category] = deadline
+		}
+	}
+}
+
+// check() returns true if sending the category is held back; otherwise
+// it returns summaries about limits that are over
+func (rl *rateLimits) check(category string, now time.Time) (limited bool, summaries []string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for _, c := range []string{"", category} {
+		if deadline, ok := rl.until[c]; ok && now.Before(deadline) {
+			rl.suppress(category)
+			return true, nil
+		}
+	}
+
+	var expired []string
+	for c, deadline := range rl.until {
+		if !now.Before(deadline) {
+			expired = append(expired, c)
+		}
+	}
+	sort.Strings(expired)
+
+	for _, c := range expired {
+		delete(rl.until, c)
+
+		// limit for all categories suppresses events under their real category
+		if c == "" {
+			c = category
+		}
+		if n := rl.suppressed[c]; n > 0 {
+			summaries = append(summaries, fmt.Sprintf("sentry: rate limit for category \"%s\" is over, %d events suppressed", c, n))
+			delete(rl.suppressed, c)
+		}
+	}
+	return false, summaries
+}
+
+func (rl *rateLimits) suppress(category string) {
+	if rl.suppressed == nil {
+		rl.suppressed = map[string]int{}
+	}
+	rl.suppressed[category]++
+	atomic.AddUint64(&rateLimitedEvents, 1)
+}
+
+// the event, rejected with 429, is counted as suppressed too
+func (rl *rateLimits) reject(category string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.suppress(category)
+}
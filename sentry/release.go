@@ -0,0 +1,111 @@
+package sentry
+
+import (
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/getsentry/raven-go"
+)
+
+// explicit values, see SetRelease() and friends
+var release, environment, serverName string
+
+var buildRelease string
+var buildReleaseOnce sync.Once
+
+var hostname, _ = os.Hostname()
+
+// SetRelease() overrides release of events, which is SENTRY_RELEASE or
+// detected from build info by default
+func SetRelease(r string) {
+	release = r
+}
+
+// SetEnvironment() overrides environment of events, SENTRY_ENVIRONMENT by default
+func SetEnvironment(env string) {
+	environment = env
+}
+
+// SetServerName() overrides server_name of events, hostname by default
+func SetServerName(name string) {
+	serverName = name
+}
+
+// ReleaseOf() makes release from build info of the binary: main module version,
+// e.g. "github.com/me/app@v1.2.3", or VCS revision if it is a development build
+func ReleaseOf(info *debug.BuildInfo) string {
+	if info == nil || info.Main.Path == "" {
+		return ""
+	}
+
+	if version := info.Main.Version; version != "" && version != "(devel)" {
+		return info.Main.Path + "@" + version
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return ""
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return info.Main.Path + "@" + revision
+}
+
+// Release() returns release of events; empty if it is unknown
+func Release() string {
+	if release != "" {
+		return release
+	}
+	if r := os.Getenv("SENTRY_RELEASE"); r != "" {
+		return r
+	}
+
+	buildReleaseOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			buildRelease = ReleaseOf(info)
+		}
+	})
+	return buildRelease
+}
+
+// Environment() returns environment of events, e.g. "production"; empty if it is unknown
+func Environment() string {
+	if environment != "" {
+		return environment
+	}
+	return os.Getenv("SENTRY_ENVIRONMENT")
+}
+
+// ServerName() returns server_name of events
+func ServerName() string {
+	if serverName != "" {
+		return serverName
+	}
+	return hostname
+}
+
+// explicit packet values win
+func stampPacket(packet *raven.Packet) {
+	if packet.Release == "" {
+		packet.Release = Release()
+	}
+	if packet.Environment == "" {
+		packet.Environment = Environment()
+	}
+	if packet.ServerName == "" {
+		packet.ServerName = ServerName()
+	}
+}
@@ -0,0 +1,85 @@
+package sentry
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/getsentry/raven-go"
+)
+
+// SamplingOptions are sample rates of events, from 0 (send nothing) to 1 (send all).
+// The most specific rate wins: by aggregation key, then by module, then by level;
+// events without any rate are always sent
+type SamplingOptions struct {
+	Levels map[raven.Severity]float64
+	// go-logging module ("module" tag) => level => rate
+	Modules map[string]map[raven.Severity]float64
+	// aggregation key, i.e. message template, => rate
+	Keys map[string]float64
+}
+
+// nil means no sampling
+var sampling *SamplingOptions
+
+var sampledEvents uint64
+
+// :TRICKY: tests replace it to be deterministic
+var sampleRandom = rand.Float64
+
+// SetSampling() turns on sampling of events, e.g. of a noisy warning in a hot path;
+// the sample rate of a sent event is recorded as "sample_rate" extra
+func SetSampling(opts SamplingOptions) {
+	sampling = &opts
+}
+
+// SampledEvents() returns how many events were not sent because of sampling
+func SampledEvents() uint64 {
+	return atomic.LoadUint64(&sampledEvents)
+}
+
+// aggregation key of the packet: message template for warnings, message for errors
+func aggregationKey(packet *raven.Packet) string {
+	for _, iface := range packet.Interfaces {
+		if msg, ok := iface.(*raven.Message); ok {
+			return msg.Message
+		}
+	}
+	return packet.Message
+}
+
+func (s *SamplingOptions) rate(packet *raven.Packet, tags map[string]string) (float64, bool) {
+	if rate, ok := s.Keys[aggregationKey(packet)]; ok {
+		return rate, true
+	}
+	if levels, ok := s.Modules[tags["module"]]; ok {
+		if rate, ok := levels[packet.Level]; ok {
+			return rate, true
+		}
+	}
+	rate, ok := s.Levels[packet.Level]
+	return rate, ok
+}
+
+// sample() returns false if the packet is sampled out
+func sample(packet *raven.Packet, tags map[string]string) bool {
+	s := sampling
+	if s == nil {
+		return true
+	}
+
+	rate, ok := s.rate(packet, tags)
+	if !ok || rate >= 1 {
+		return true
+	}
+
+	if rate <= 0 || sampleRandom() >= rate {
+		atomic.AddUint64(&sampledEvents, 1)
+		return false
+	}
+
+	if packet.Extra == nil {
+		packet.Extra = raven.Extra{}
+	}
+	packet.Extra["sample_rate"] = rate
+	return true
+}
@@ -0,0 +1,144 @@
+package sentry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Scope is event data attached to context.Context, e.g. per HTTP request;
+// Capture*Ctx() functions merge it into their events
+type Scope struct {
+	Tags    map[string]string
+	Extra   map[string]interface{}
+	User    *raven.User
+	Request *raven.Http
+}
+
+type scopeKey struct{}
+
+// ScopeFromContext() returns scope of ctx or nil; do not modify it,
+// use WithScope() instead
+func ScopeFromContext(ctx context.Context) *Scope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+func (s *Scope) clone() *Scope {
+	res := &Scope{
+		Tags:  map[string]string{},
+		Extra: map[string]interface{}{},
+	}
+	if s == nil {
+		return res
+	}
+
+	for k, v := range s.Tags {
+		res.Tags[k] = v
+	}
+	for k, v := range s.Extra {
+		res.Extra[k] = v
+	}
+	res.User = s.User
+	res.Request = s.Request
+	return res
+}
+
+// WithScope() returns child context with the copy of parent scope, changed by update(), e.g.
+//
+//	ctx = sentry.WithScope(ctx, func(scope *sentry.Scope) {
+//		scope.Tags["request_id"] = requestID
+//	})
+func WithScope(ctx context.Context, update func(scope *Scope)) context.Context {
+	scope := ScopeFromContext(ctx).clone()
+	update(scope)
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	return WithScope(ctx, func(scope *Scope) {
+		for k, v := range tags {
+			scope.Tags[k] = v
+		}
+	})
+}
+
+func WithExtra(ctx context.Context, key string, value interface{}) context.Context {
+	return WithScope(ctx, func(scope *Scope) {
+		scope.Extra[key] = value
+	})
+}
+
+func WithUser(ctx context.Context, user *raven.User) context.Context {
+	return WithScope(ctx, func(scope *Scope) {
+		scope.User = user
+	})
+}
+
+func WithRequest(ctx context.Context, req *http.Request) context.Context {
+	return WithScope(ctx, func(scope *Scope) {
+		scope.Request = raven.NewHttp(req)
+	})
+}
+
+// apply() merges scope into packet and returns tags to capture with;
+// explicit capture data wins over the scope
+func (s *Scope) apply(packet *raven.Packet, tags map[string]string) map[string]string {
+	if s == nil {
+		return tags
+	}
+
+	if len(s.Tags) != 0 {
+		merged := map[string]string{}
+		for k, v := range s.Tags {
+			merged[k] = v
+		}
+		for k, v := range tags {
+			merged[k] = v
+		}
+		tags = merged
+	}
+
+	if packet.Extra == nil {
+		packet.Extra = raven.Extra{}
+	}
+	for k, v := range s.Extra {
+		if _, exists := packet.Extra[k]; !exists {
+			packet.Extra[k] = v
+		}
+	}
+
+	hasClass := func(class string) bool {
+		for _, iface := range packet.Interfaces {
+			if iface != nil && iface.Class() == class {
+				return true
+			}
+		}
+		return false
+	}
+	if s.User != nil && !hasClass(s.User.Class()) {
+		packet.Interfaces = append(packet.Interfaces, s.User)
+	}
+	if s.Request != nil && !hasClass(s.Request.Class()) {
+		packet.Interfaces = append(packet.Interfaces, s.Request)
+	}
+
+	return tags
+}
+
+// CaptureAndWaitCtx() = CaptureAndWait() with the scope of ctx
+func CaptureAndWaitCtx(ctx context.Context, packet *raven.Packet, tags map[string]string) string {
+	return DefaultClient().CaptureAndWaitCtx(ctx, packet, tags)
+}
+
+// ScopeHandler() attaches the request data to the scope of request context,
+// so that errors captured with r.Context() while serving it carry them
+func ScopeHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithRequest(r.Context(), r)))
+	})
+}
@@ -5,11 +5,14 @@ Sentry is crash reporting and aggregation platform, https://sentry.io
 package sentry
 
 import (
+	"context"
+	"fmt"
 	"github.com/getsentry/raven-go"
 	"runtime"
 	"path"
 	"log"
 	"time"
+	"net/http"
 )
 
 // Handler type to hook events when sending to Sentry failed
@@ -21,22 +24,36 @@ func SetSEH(seh SentryErrorHandlerType) {
 	SentryErrorHandler = seh
 }
 
+// CaptureAndWait() sends packet to Sentry and returns eventID
+// In asynchronous mode (see StartQueue()) it returns right after the packet is queued,
+// except for FATAL packets: the process is likely to exit right after them.
+// Sampled out packets and ones dropped by processors are not sent, "" is returned,
+// see SetSampling() and AddProcessor()
 func CaptureAndWait(packet *raven.Packet, tags map[string]string) string {
-	client := raven.DefaultClient
-
-	if client == nil {
-		return ""
-	}
+	return DefaultClient().CaptureAndWait(packet, tags)
+}
 
+func captureAndWait(c *Client, packet *raven.Packet, tags map[string]string) string {
 	//if client.shouldExcludeErr(err.Error()) {
 	//	return ""
 	//}
 
-	eventID, ch := client.Capture(packet, tags)
+	start := time.Now()
+	eventID, ch := c.Raven.Capture(packet, tags)
 	err := <-ch
-
-	if err != nil && SentryErrorHandler != nil {
-		SentryErrorHandler(err)
+	countDelivery(packet, err, time.Since(start))
+
+	// rate limited events are just counted, see HTTPTransport
+	if err != nil && err != ErrRateLimited {
+		c.handleError(err)
+
+		// resending rejected packets doesn't help; spool is replayed to the default DSN,
+		// see SetSpool()
+		if !IsPermanentError(err) && c.Raven == raven.DefaultClient {
+			if spoolErr := spoolPacket(packet); spoolErr != nil {
+				c.handleError(fmt.Errorf("sentry spool: %s", spoolErr))
+			}
+		}
 	}
 
 	return eventID
@@ -44,7 +61,7 @@ func CaptureAndWait(packet *raven.Packet, tags map[string]string) string {
 
 func Interface2Packet(message string, iObject raven.Interface, level raven.Severity) *raven.Packet {
 	// :TRICKY: original CaptureError() use Exception type, which needs proper error type,
-	// but we do not have it for go-logging and log packages
+	// but we do not have it for go-logging and log packages; if we do, see NewExceptionPacket()
 	packet := raven.NewPacket(message, iObject)
 	packet.Level = level
 
@@ -54,22 +71,65 @@ func Interface2Packet(message string, iObject raven.Interface, level raven.Sever
 // CaptureErrorAndWait() sends message to Sentry and returns eventID
 // Aggregating is done by stacktrace
 func CaptureErrorAndWait(message string, tags map[string]string, calldepth int, level raven.Severity) string {
-	client := raven.DefaultClient
+	return DefaultClient().CaptureErrorAndWaitEx(message, tags, calldepth+1, level, Grouping{})
+}
+
+// CaptureErrorAndWaitEx() = CaptureErrorAndWait() with grouping control
+func CaptureErrorAndWaitEx(message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) string {
+	return DefaultClient().CaptureErrorAndWaitEx(message, tags, calldepth+1, level, grouping)
+}
+
+// CaptureErrorAndWaitCtx() = CaptureErrorAndWait() with the scope of ctx
+func CaptureErrorAndWaitCtx(ctx context.Context, message string, tags map[string]string, calldepth int, level raven.Severity) string {
+	return DefaultClient().CaptureErrorAndWaitCtx(ctx, message, tags, calldepth+1, level)
+}
+
+// CaptureExceptionAndWait() sends err to Sentry as exception with the wrap chain
+// and returns eventID; message is what is logged
+func CaptureExceptionAndWait(err error, message string, tags map[string]string, calldepth int, level raven.Severity) string {
+	return DefaultClient().CaptureExceptionAndWaitEx(err, message, tags, calldepth+1, level, Grouping{})
+}
+
+// CaptureExceptionAndWaitEx() = CaptureExceptionAndWait() with grouping control
+func CaptureExceptionAndWaitEx(err error, message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) string {
+	return DefaultClient().CaptureExceptionAndWaitEx(err, message, tags, calldepth+1, level, grouping)
+}
 
-	if client == nil {
-		return ""
+// NewErrorPacket() makes packet with stacktrace for CaptureAndWait()
+func NewErrorPacket(message string, tags map[string]string, calldepth int, level raven.Severity, grouping Grouping) *raven.Packet {
+	var includePaths []string
+	if client := raven.DefaultClient; client != nil {
+		includePaths = client.IncludePaths()
 	}
 
-	stacktrace := raven.NewStacktrace(calldepth, 3, client.IncludePaths())
-	return CaptureAndWait(Interface2Packet(message, stacktrace, level), tags)
+	stacktrace := raven.NewStacktrace(calldepth, 3, includePaths)
+	packet := Interface2Packet(message, stacktrace, level)
+	packet.Fingerprint = grouping.fingerprint(message, tags, calldepth)
+
+	return packet
 }
 
 // CaptureMessageAndWait() sends message to Sentry and returns eventID
 // Aggregating is done by iObject.Message attribute
 // Additional info is filename and line number
 func CaptureMessageAndWait(message string, tags map[string]string, calldepth int, iObject *raven.Message) string {
-	packet := Interface2Packet(message, iObject, raven.WARNING)
+	return DefaultClient().CaptureMessageAndWaitEx(message, tags, calldepth+1, iObject, Grouping{})
+}
+
+// CaptureMessageAndWaitEx() = CaptureMessageAndWait() with grouping control;
+// grouping.Format is iObject.Message by default
+func CaptureMessageAndWaitEx(message string, tags map[string]string, calldepth int, iObject *raven.Message, grouping Grouping) string {
+	return DefaultClient().CaptureMessageAndWaitEx(message, tags, calldepth+1, iObject, grouping)
+}
+
+// CaptureMessageAndWaitCtx() = CaptureMessageAndWait() with the scope of ctx
+func CaptureMessageAndWaitCtx(ctx context.Context, message string, tags map[string]string, calldepth int, iObject *raven.Message) string {
+	return DefaultClient().CaptureMessageAndWaitCtx(ctx, message, tags, calldepth+1, iObject)
+}
 
+// NewMessagePacket() makes WARNING packet with message interface for CaptureAndWait()
+func NewMessagePacket(message string, tags map[string]string, calldepth int, iObject *raven.Message, grouping Grouping) *raven.Packet {
+	packet := Interface2Packet(message, iObject, raven.WARNING)
 
 	var fn string
 	pc, pathname, line, ok := runtime.Caller(calldepth)
@@ -85,20 +145,82 @@ func CaptureMessageAndWait(message string, tags map[string]string, calldepth int
 		extra["pathname"] = pathname
 	}
 
-	return CaptureAndWait(packet, tags)
+	if grouping.Format == "" {
+		grouping.Format = iObject.Message
+	}
+	packet.Fingerprint = grouping.fingerprint(message, tags, calldepth)
+
+	return packet
 }
 
+// DSN set by MustSetDSN()
+var currentDSN *DSN
+
 // = raven.SetDSN(dsn) with checks
+// DSN may choose Sentry protocol with "protocol" parameter, e.g.
+// https://key@sentry.io/1?protocol=envelope ; store protocol is used by default
 func MustSetDSN(dsn string) {
-	err := raven.SetDSN(dsn)
+	d, err := ParseDSN(dsn)
+	if err == nil {
+		err = raven.SetDSN(d.Raven)
+	}
+	if err != nil {
+		log.Fatalf("Bad Sentry DSN '%s': %s", dsn, err)
+	}
+
+	transport, err := makeTransport(d, raven.DefaultClient.Transport)
 	if err != nil {
 		log.Fatalf("Bad Sentry DSN '%s': %s", dsn, err)
 	}
+	raven.DefaultClient.Transport = transport
+	currentDSN = d
+
+	checkDSN(dsn)
+
+	if spool != nil {
+		replaySpoolInBackground()
+	}
+}
+
+// makeTransport() makes registered transport, if DSN asks for it, or HTTP one
+func makeTransport(d *DSN, prev raven.Transport) (raven.Transport, error) {
+	if d.Transport == "" {
+		return newTransport(d.Protocol, prev), nil
+	}
+
+	factory, ok := registeredTransport(d.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unknown Sentry transport: %s", d.Transport)
+	}
+	return factory(d), nil
+}
+
+// newTransport() makes HTTPTransport with a new http.Client, based on the one of
+// the previous transport, e.g. with gocertifi roots of raven
+func newTransport(protocol string, prev raven.Transport) *HTTPTransport {
+	var prevClient *http.Client
+	switch t := prev.(type) {
+	case *raven.HTTPTransport:
+		prevClient = t.Client
+	case *HTTPTransport:
+		prevClient = t.Client
+	}
+	httpClient := &http.Client{}
+	if prevClient != nil {
+		*httpClient = *prevClient
+	}
 
 	// we don't want to get stuck if not working DSN
 	// 5 seconds should be enough to send to Sentry
-	ht, ok := raven.DefaultClient.Transport.(*raven.HTTPTransport)
-	if ok {
-		ht.Timeout = time.Second * 5
+	httpClient.Timeout = time.Second * 5
+
+	// proxy and TLS, see SetHTTPOptions()
+	if l := httpSettings; l != nil {
+		httpClient.Transport = l.apply(httpClient.Transport)
+	}
+
+	return &HTTPTransport{
+		Protocol: protocol,
+		Client:   httpClient,
 	}
 }
@@ -0,0 +1,242 @@
+/*
+Package sentrytest records Sentry events in memory, to assert them in unit tests:
+
+	func TestReport(t *testing.T) {
+		sentrytest.Setup(t)
+		slog.SetupGoLogging("", sentrytest.DSN, true)
+
+		logging.MustGetLogger("db").Errorf("query failed: %s", err)
+
+		sentrytest.RequireEvent(t, raven.ERROR, "^query failed")
+	}
+*/
+package sentrytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+	"github.com/stretchr/testify/require"
+)
+
+// TransportName is the value of DSN "transport" parameter to record events
+const TransportName = "sentrytest"
+
+// DSN to pass to sentry.MustSetDSN() or slog.Setup*()
+const DSN = "http://public@sentrytest/1?transport=" + TransportName
+
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace"`
+}
+
+type LogEntry struct {
+	Message string        `json:"message"`
+	Params  []interface{} `json:"params"`
+}
+
+// Event is a decoded packet, as Sentry gets it
+type Event struct {
+	EventID     string                 `json:"event_id"`
+	Level       raven.Severity         `json:"level"`
+	Message     string                 `json:"message"`
+	Culprit     string                 `json:"culprit"`
+	Logger      string                 `json:"logger"`
+	Release     string                 `json:"release"`
+	Environment string                 `json:"environment"`
+	ServerName  string                 `json:"server_name"`
+	Extra       map[string]interface{} `json:"extra"`
+	Fingerprint []string               `json:"fingerprint"`
+	Stacktrace  *Stacktrace            `json:"stacktrace"`
+	LogEntry    *LogEntry              `json:"logentry"`
+	User        map[string]interface{} `json:"user"`
+	Request     map[string]interface{} `json:"request"`
+
+	Tags map[string]string `json:"-"`
+	// innermost first
+	Exceptions  []Exception         `json:"-"`
+	Breadcrumbs []sentry.Breadcrumb `json:"-"`
+
+	// the whole packet JSON
+	Raw map[string]interface{} `json:"-"`
+}
+
+func (e *Event) String() string {
+	return fmt.Sprintf("%s: %s", e.Level, e.Message)
+}
+
+// Decode() makes Event of packet JSON
+func Decode(data []byte) (*Event, error) {
+	event := &Event{}
+	err := json.Unmarshal(data, event)
+	if err != nil {
+		return nil, err
+	}
+
+	var rest struct {
+		Tags        [][2]string     `json:"tags"`
+		Exception   json.RawMessage `json:"exception"`
+		Breadcrumbs struct {
+			Values []sentry.Breadcrumb `json:"values"`
+		} `json:"breadcrumbs"`
+	}
+	err = json.Unmarshal(data, &rest)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Tags = map[string]string{}
+	for _, tag := range rest.Tags {
+		event.Tags[tag[0]] = tag[1]
+	}
+	event.Breadcrumbs = rest.Breadcrumbs.Values
+
+	// both raven.Exception and raven.Exceptions have "exception" class
+	if len(rest.Exception) != 0 {
+		var chain struct {
+			Values []Exception `json:"values"`
+		}
+		err = json.Unmarshal(rest.Exception, &chain)
+		if err != nil {
+			return nil, err
+		}
+		if chain.Values == nil {
+			var exc Exception
+			err = json.Unmarshal(rest.Exception, &exc)
+			if err != nil {
+				return nil, err
+			}
+			chain.Values = []Exception{exc}
+		}
+		event.Exceptions = chain.Values
+	}
+
+	err = json.Unmarshal(data, &event.Raw)
+	return event, err
+}
+
+// Transport records packets instead of sending them
+type Transport struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (t *Transport) Send(url, authHeader string, packet *raven.Packet) error {
+	data, err := packet.JSON()
+	if err != nil {
+		return err
+	}
+	event, err := Decode(data)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+	return nil
+}
+
+// Events() returns recorded events, oldest first
+func (t *Transport) Events() []*Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Event(nil), t.events...)
+}
+
+func (t *Transport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = nil
+}
+
+// all clients with DSN transport=sentrytest record into it
+var recorder = &Transport{}
+
+func init() {
+	sentry.RegisterTransport(TransportName, func(dsn *sentry.DSN) raven.Transport {
+		return recorder
+	})
+}
+
+// Recorder() returns the transport, which records events
+func Recorder() *Transport {
+	return recorder
+}
+
+// Setup() sets DSN to record events and forgets events recorded before;
+// slog.Setup*() with DSN may be called instead of it, but then call Reset() yourself
+func Setup(t require.TestingT) *Transport {
+	sentry.MustSetDSN(DSN)
+	recorder.Reset()
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(recorder.Reset)
+	}
+	return recorder
+}
+
+// Events() waits for queued events, see sentry.StartQueue(), and returns recorded ones
+func Events() []*Event {
+	sentry.Flush(time.Second * 5)
+	return recorder.Events()
+}
+
+func dump(events []*Event) string {
+	var lines []string
+	for _, event := range events {
+		lines = append(lines, event.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RequireEvent() checks that there is an event of the level with message matching
+// regexp messageMatch, and returns it; empty level matches any
+func RequireEvent(t require.TestingT, level raven.Severity, messageMatch string) *Event {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	re, err := regexp.Compile(messageMatch)
+	require.NoError(t, err)
+
+	events := Events()
+	for _, event := range events {
+		if (level == "" || event.Level == level) && re.MatchString(event.Message) {
+			return event
+		}
+	}
+
+	require.FailNow(t, fmt.Sprintf("no Sentry event %s: %s", level, messageMatch), "recorded events:\n%s", dump(events))
+	return nil
+}
+
+// RequireNoEvents() checks that nothing is recorded
+func RequireNoEvents(t require.TestingT) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	events := Events()
+	if len(events) != 0 {
+		require.FailNow(t, "unexpected Sentry events", "recorded events:\n%s", dump(events))
+	}
+}
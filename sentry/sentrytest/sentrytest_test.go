@@ -0,0 +1,50 @@
+package sentrytest_test
+
+import (
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog"
+	"github.com/muravjov/slog/sentry/sentrytest"
+	"github.com/op/go-logging"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupGoLogging(t *testing.T) {
+	slog.SetupGoLogging("", sentrytest.DSN, true)
+	sentrytest.Setup(t)
+	sentrytest.RequireNoEvents(t)
+
+	logger := logging.MustGetLogger("db")
+	logger.Errorf("query failed: %s", errors.New("timeout"))
+	logger.Warningf("slow query: %d ms", 1500)
+	logger.Infof("connected")
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "^query failed")
+	require.Equal(t, "db", event.Tags["module"])
+	require.Len(t, event.Exceptions, 1)
+	require.Equal(t, "timeout", event.Exceptions[0].Value)
+
+	event = sentrytest.RequireEvent(t, raven.WARNING, "slow query")
+	require.Equal(t, "slow query: %d ms", event.LogEntry.Message)
+
+	require.Len(t, sentrytest.Events(), 2)
+
+	log.Printf("standard log error")
+	sentrytest.RequireEvent(t, raven.ERROR, "standard log error")
+}
+
+func TestSetupLogrus(t *testing.T) {
+	slog.SetupLogrus("", sentrytest.DSN)
+	sentrytest.Setup(t)
+
+	logrus.WithError(errors.New("refused")).Error("connect failed")
+	logrus.Warn("retrying")
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "connect failed")
+	require.Equal(t, "refused", event.Exceptions[0].Value)
+	sentrytest.RequireEvent(t, raven.WARNING, "retrying")
+}
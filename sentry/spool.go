@@ -0,0 +1,299 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Spool keeps packets, which failed to be delivered, as files in Dir,
+// to resend them later. Packets rejected by Sentry, see IsPermanentError(), are not kept.
+// Only packets of the default client, see MustSetDSN(), are spooled: the spool is
+// replayed to the default DSN; events of NewClient() clients are lost, if Sentry is down
+type SpoolOptions struct {
+	Dir string
+	// total size of spooled files in bytes, oldest files are removed to fit it;
+	// 0 means no limit
+	MaxSize int64
+	// older files are removed without sending; 0 means no limit
+	MaxAge time.Duration
+}
+
+var spool *SpoolOptions
+
+// serializes spool directory changes within the process
+var spoolMutex sync.Mutex
+
+const spoolExt = ".json"
+
+// SetSpool() turns on spooling of undeliverable packets of the default client.
+// If it is called before MustSetDSN(), then the latter resends spooled packets
+// in the background.
+func SetSpool(opts SpoolOptions) error {
+	if opts.Dir == "" {
+		spool = nil
+		return nil
+	}
+
+	err := os.MkdirAll(opts.Dir, os.FileMode(0750))
+	if err != nil {
+		return err
+	}
+
+	spool = &opts
+	return nil
+}
+
+// GetSpool() returns current spool options, Dir is empty if spooling is off
+func GetSpool() SpoolOptions {
+	if s := spool; s != nil {
+		return *s
+	}
+	return SpoolOptions{}
+}
+
+type spoolFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// oldest first
+func listSpool(dir string) ([]spoolFile, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []spoolFile
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != spoolExt {
+			continue
+		}
+		files = append(files, spoolFile{
+			path:    filepath.Join(dir, info.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	// names start with timestamp
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].path < files[j].path
+	})
+	return files, nil
+}
+
+func isExpired(opts *SpoolOptions, f spoolFile) bool {
+	return opts.MaxAge > 0 && time.Now().Sub(f.modTime) > opts.MaxAge
+}
+
+// removes expired files and the oldest ones to fit MaxSize
+func trimSpool(opts *SpoolOptions) error {
+	files, err := listSpool(opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	for _, f := range files {
+		if !isExpired(opts, f) && (opts.MaxSize <= 0 || total <= opts.MaxSize) {
+			break
+		}
+		os.Remove(f.path)
+		total -= f.size
+	}
+	return nil
+}
+
+func spoolPacket(packet *raven.Packet) error {
+	opts := spool
+	if opts == nil {
+		return nil
+	}
+
+	packetJSON, err := packet.JSON()
+	if err != nil {
+		return err
+	}
+
+	spoolMutex.Lock()
+	defer spoolMutex.Unlock()
+
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), packet.EventID)
+	// :TRICKY: write and rename, not to let replay read a half written file
+	tmpPath := filepath.Join(opts.Dir, name+".tmp")
+	err = ioutil.WriteFile(tmpPath, packetJSON, os.FileMode(0640))
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpPath, filepath.Join(opts.Dir, name+spoolExt))
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return trimSpool(opts)
+}
+
+// ReplaySpool() resends spooled packets to the default DSN, oldest first, and removes
+// the sent ones. Packets rejected by Sentry are removed too, see IsPermanentError();
+// it stops on the first transient error, e.g. network failure, 5xx or rate limit,
+// the rest are left for the next time.
+// :TRICKY: spoolMutex is not held while sending, not to stall spoolPacket() of
+// logging calls; a file may be trimmed meanwhile, then it's skipped
+func ReplaySpool() (sent int, err error) {
+	opts := spool
+	dsn := currentDSN
+	client := raven.DefaultClient
+	if opts == nil || dsn == nil || dsn.StoreURL == "" || client == nil {
+		return
+	}
+
+	spoolMutex.Lock()
+	files, err := listSpool(opts.Dir)
+	spoolMutex.Unlock()
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		var packet *raven.Packet
+		packet, err = readSpoolFile(opts, f)
+		if err != nil {
+			return
+		}
+		if packet == nil {
+			continue
+		}
+
+		err = client.Transport.Send(dsn.StoreURL, dsn.AuthHeader, packet)
+		if IsPermanentError(err) {
+			// not to block the spool forever
+			removeSpoolFile(f)
+			if SentryErrorHandler != nil {
+				SentryErrorHandler(fmt.Errorf("sentry spool: event %s is rejected and removed: %s", packet.EventID, err))
+			}
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		removeSpoolFile(f)
+		sent++
+	}
+	return
+}
+
+// readSpoolFile() returns nil packet, if the file is gone, expired or broken
+func readSpoolFile(opts *SpoolOptions, f spoolFile) (*raven.Packet, error) {
+	spoolMutex.Lock()
+	defer spoolMutex.Unlock()
+
+	if isExpired(opts, f) {
+		os.Remove(f.path)
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := DecodePacket(data)
+	if err != nil {
+		// no use to keep it
+		os.Remove(f.path)
+		return nil, nil
+	}
+	return packet, nil
+}
+
+func removeSpoolFile(f spoolFile) {
+	spoolMutex.Lock()
+	defer spoolMutex.Unlock()
+	os.Remove(f.path)
+}
+
+func replaySpoolInBackground() {
+	go func() {
+		_, err := ReplaySpool()
+		if err != nil && SentryErrorHandler != nil {
+			SentryErrorHandler(fmt.Errorf("sentry spool: replay stopped: %s", err))
+		}
+	}()
+}
+
+// raw JSON of an interface, like "exception" or "stacktrace"
+type rawInterface struct {
+	class string
+	data  json.RawMessage
+}
+
+func (i *rawInterface) Class() string { return i.class }
+
+func (i *rawInterface) MarshalJSON() ([]byte, error) {
+	return i.data, nil
+}
+
+var packetFields = func() map[string]bool {
+	res := map[string]bool{}
+	typ := reflect.TypeOf(raven.Packet{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			res[name] = true
+		}
+	}
+	return res
+}()
+
+// DecodePacket() makes packet from packet.JSON() output; interfaces
+// are kept as is, in JSON
+func DecodePacket(data []byte) (*raven.Packet, error) {
+	packet := &raven.Packet{}
+	err := json.Unmarshal(data, packet)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var classes []string
+	for class := range fields {
+		if !packetFields[class] {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+
+	for _, class := range classes {
+		packet.Interfaces = append(packet.Interfaces, &rawInterface{
+			class: class,
+			data:  fields[class],
+		})
+	}
+	return packet, nil
+}
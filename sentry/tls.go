@@ -0,0 +1,102 @@
+package sentry
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	urlModule "net/url"
+
+	"github.com/muravjov/slog/tlsconfig"
+)
+
+// Network settings of HTTP transport, e.g. for self-hosted Sentry behind
+// an internal CA and an egress proxy
+type HTTPOptions struct {
+	// TOML file like stress one: ca, cert, key, skip_verify, server_name;
+	// see tlsconfig package
+	TLSConfigFile string
+	// e.g. http://proxy:3128; HTTP_PROXY/HTTPS_PROXY environment is used if empty
+	Proxy string
+}
+
+var httpOptions *HTTPOptions
+var httpSettings *loadedHTTPOptions
+
+// HTTPOptions with the files read
+type loadedHTTPOptions struct {
+	// nil means TLS settings of the base transport
+	tlsConfig *tls.Config
+	proxy     *urlModule.URL
+}
+
+func loadHTTPOptions(opts HTTPOptions) (*loadedHTTPOptions, error) {
+	res := &loadedHTTPOptions{}
+	if opts.TLSConfigFile != "" {
+		tlsConfig, err := tlsconfig.NewClientConfig(opts.TLSConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		res.tlsConfig = tlsConfig
+	}
+
+	if opts.Proxy != "" {
+		proxy, err := urlModule.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy URL %s: %s", opts.Proxy, err)
+		}
+		res.proxy = proxy
+	}
+	return res, nil
+}
+
+// apply() makes a copy of base with the options; TLS settings of base, e.g. gocertifi roots
+// of raven transport, are kept, unless TLS file is given
+func (l *loadedHTTPOptions) apply(base http.RoundTripper) *http.Transport {
+	t, ok := base.(*http.Transport)
+	if !ok {
+		t = http.DefaultTransport.(*http.Transport)
+	}
+	res := t.Clone()
+
+	if l.tlsConfig != nil {
+		res.TLSClientConfig = l.tlsConfig.Clone()
+	}
+	if l.proxy != nil {
+		res.Proxy = http.ProxyURL(l.proxy)
+	}
+	return res
+}
+
+// NewRoundTripper() makes http.Transport with the options on top of http.DefaultTransport
+func NewRoundTripper(opts HTTPOptions) (*http.Transport, error) {
+	l, err := loadHTTPOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return l.apply(nil), nil
+}
+
+// SetHTTPOptions() configures HTTP transports made afterwards, so call it before
+// MustSetDSN(), NewClient() or slog.Setup*(); nil means defaults
+func SetHTTPOptions(opts *HTTPOptions) error {
+	if opts == nil {
+		httpOptions = nil
+		httpSettings = nil
+		return nil
+	}
+
+	l, err := loadHTTPOptions(*opts)
+	if err != nil {
+		return err
+	}
+
+	c := *opts
+	httpOptions = &c
+	httpSettings = l
+	return nil
+}
+
+// GetHTTPOptions() returns options set by SetHTTPOptions(), nil if none
+func GetHTTPOptions() *HTTPOptions {
+	return httpOptions
+}
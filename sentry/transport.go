@@ -0,0 +1,335 @@
+package sentry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	urlModule "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Sentry protocols
+const (
+	// legacy /api/<id>/store/ endpoint, raven-go uses it
+	StoreProtocol = "store"
+	// /api/<id>/envelope/ endpoint, preferred by newer Sentry and Relay
+	EnvelopeProtocol = "envelope"
+)
+
+var userAgent = "slog/1.0"
+
+// DSN query parameter to choose protocol, e.g.
+// https://key@sentry.io/1?protocol=envelope
+const protocolParam = "protocol"
+
+// DSN query parameter to choose registered transport instead of HTTP one, e.g.
+// http://public@localhost/1?transport=sentrytest
+const transportParam = "transport"
+
+// TransportFactory makes transport for DSN
+type TransportFactory func(dsn *DSN) raven.Transport
+
+var transportsMutex sync.RWMutex
+var transports = map[string]TransportFactory{}
+
+// RegisterTransport() makes transport available via DSN "transport" parameter,
+// e.g. in-memory one for tests, see sentrytest package
+func RegisterTransport(name string, factory TransportFactory) {
+	transportsMutex.Lock()
+	defer transportsMutex.Unlock()
+	transports[name] = factory
+}
+
+func registeredTransport(name string) (TransportFactory, bool) {
+	transportsMutex.RLock()
+	defer transportsMutex.RUnlock()
+	factory, ok := transports[name]
+	return factory, ok
+}
+
+type DSN struct {
+	// DSN without slog specific parameters, to be passed to raven
+	Raven string
+
+	Protocol string
+	// registered transport name, HTTP transport is used if empty
+	Transport  string
+	ProjectID  string
+	StoreURL   string
+	AuthHeader string
+}
+
+// :COPY_N_PASTE: raven.Client.SetDSN()
+func ParseDSN(dsn string) (*DSN, error) {
+	res := &DSN{
+		Protocol: StoreProtocol,
+	}
+	if dsn == "" {
+		return res, nil
+	}
+
+	uri, err := urlModule.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	query := uri.Query()
+	if protocol := query.Get(protocolParam); protocol != "" {
+		switch protocol {
+		case StoreProtocol, EnvelopeProtocol:
+			res.Protocol = protocol
+		default:
+			return nil, fmt.Errorf("unknown Sentry protocol: %s", protocol)
+		}
+
+		query.Del(protocolParam)
+		uri.RawQuery = query.Encode()
+	}
+	if transport := query.Get(transportParam); transport != "" {
+		res.Transport = transport
+
+		query.Del(transportParam)
+		uri.RawQuery = query.Encode()
+	}
+	res.Raven = uri.String()
+
+	if uri.User == nil {
+		return nil, raven.ErrMissingUser
+	}
+	publicKey := uri.User.Username()
+	secretKey, hasSecretKey := uri.User.Password()
+	uri.User = nil
+
+	if idx := strings.LastIndex(uri.Path, "/"); idx != -1 {
+		res.ProjectID = uri.Path[idx+1:]
+		uri.Path = uri.Path[:idx+1] + "api/" + res.ProjectID + "/store/"
+	}
+	if res.ProjectID == "" {
+		return nil, raven.ErrMissingProjectID
+	}
+
+	res.StoreURL = uri.String()
+
+	if hasSecretKey {
+		res.AuthHeader = fmt.Sprintf("Sentry sentry_version=4, sentry_key=%s, sentry_secret=%s", publicKey, secretKey)
+	} else {
+		res.AuthHeader = fmt.Sprintf("Sentry sentry_version=4, sentry_key=%s", publicKey)
+	}
+
+	return res, nil
+}
+
+// EnvelopeURL() turns store endpoint URL into envelope one
+func EnvelopeURL(storeURL string) string {
+	uri, err := urlModule.Parse(storeURL)
+	if err != nil || !strings.HasSuffix(uri.Path, "/store/") {
+		return storeURL
+	}
+
+	uri.Path = strings.TrimSuffix(uri.Path, "store/") + "envelope/"
+	return uri.String()
+}
+
+// envelope endpoint wants protocol version 7
+func envelopeAuthHeader(authHeader string) string {
+	return strings.Replace(authHeader, "sentry_version=4", "sentry_version=7", 1)
+}
+
+// NewEnvelope() serializes packet as an envelope with a single event item, see
+// https://develop.sentry.dev/sdk/envelopes/
+func NewEnvelope(packet *raven.Packet) ([]byte, error) {
+	packetJSON, err := packet.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling packet %+v to JSON: %v", packet, err)
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"event_id": packet.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	itemHeader, err := json.Marshal(map[string]interface{}{
+		"type":         "event",
+		"length":       len(packetJSON),
+		"content_type": "application/json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	for _, line := range [][]byte{header, itemHeader, packetJSON} {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// :COPY_N_PASTE: raven.serializedPacket()
+func serializeStore(packet *raven.Packet) (io.Reader, string, error) {
+	packetJSON, err := packet.JSON()
+	if err != nil {
+		return nil, "", fmt.Errorf("error marshaling packet %+v to JSON: %v", packet, err)
+	}
+
+	// Only deflate/base64 the packet if it is bigger than 1KB, as there is
+	// overhead.
+	if len(packetJSON) > 1000 {
+		buf := &bytes.Buffer{}
+		b64 := base64.NewEncoder(base64.StdEncoding, buf)
+		deflate, _ := zlib.NewWriterLevel(b64, zlib.BestCompression)
+		deflate.Write(packetJSON)
+		deflate.Close()
+		b64.Close()
+		return buf, "application/octet-stream", nil
+	}
+	return bytes.NewReader(packetJSON), "application/json", nil
+}
+
+// NewRequest() makes HTTP request to deliver packet with the protocol;
+// url and authHeader are for store endpoint, like raven.Transport.Send() gets them
+func NewRequest(protocol, url, authHeader string, packet *raven.Packet) (*http.Request, error) {
+	var body io.Reader
+	var contentType, contentEncoding string
+
+	switch protocol {
+	case EnvelopeProtocol:
+		url = EnvelopeURL(url)
+		authHeader = envelopeAuthHeader(authHeader)
+
+		envelope, err := NewEnvelope(packet)
+		if err != nil {
+			return nil, err
+		}
+		contentType = "application/x-sentry-envelope"
+
+		if len(envelope) > 1000 {
+			buf := &bytes.Buffer{}
+			gz := gzip.NewWriter(buf)
+			gz.Write(envelope)
+			gz.Close()
+
+			body = buf
+			contentEncoding = "gzip"
+		} else {
+			body = bytes.NewReader(envelope)
+		}
+	case StoreProtocol, "":
+		var err error
+		body, contentType, err = serializeStore(packet)
+		if err != nil {
+			return nil, fmt.Errorf("error serializing packet: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown Sentry protocol: %s", protocol)
+	}
+
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, fmt.Errorf("can't create new request: %v", err)
+	}
+	req.Header.Set("X-Sentry-Auth", authHeader)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	return req, nil
+}
+
+// HTTPError is Sentry answer other than 200 and 429
+type HTTPError struct {
+	StatusCode int
+	// X-Sentry-Error header
+	SentryError string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("raven: got http status %d - x-sentry-error: %s", e.StatusCode, e.SentryError)
+}
+
+// IsPermanentError() tells Sentry rejected the packet itself, e.g. 400 for a broken one,
+// 413 for a too large one, 401/403 for a revoked key: resending doesn't help, unlike
+// network failures, 5xx and rate limits
+func IsPermanentError(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	code := httpErr.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// HTTPTransport is like raven.HTTPTransport, but is able to deliver
+// packets via both store and envelope protocols.
+// It also honors Sentry rate limits: while held back, Send() returns ErrRateLimited
+// without any request
+type HTTPTransport struct {
+	Protocol string
+	*http.Client
+	// nil means SentryErrorHandler
+	ErrorHandler SentryErrorHandlerType
+
+	limits rateLimits
+}
+
+func (t *HTTPTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	if url == "" {
+		return nil
+	}
+
+	limited, summaries := t.limits.check(eventCategory, time.Now())
+	seh := t.ErrorHandler
+	if seh == nil {
+		seh = SentryErrorHandler
+	}
+	if seh != nil {
+		for _, summary := range summaries {
+			seh(errors.New(summary))
+		}
+	}
+	if limited {
+		return ErrRateLimited
+	}
+
+	req, err := NewRequest(t.Protocol, url, authHeader, packet)
+	if err != nil {
+		return err
+	}
+
+	res, err := t.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	t.limits.update(res, time.Now())
+	if res.StatusCode == http.StatusTooManyRequests {
+		t.limits.reject(eventCategory)
+		return ErrRateLimited
+	}
+	if res.StatusCode != 200 {
+		return &HTTPError{
+			StatusCode:  res.StatusCode,
+			SentryError: res.Header.Get("X-Sentry-Error"),
+		}
+	}
+	countBytes(packet, req.ContentLength)
+	return nil
+}
@@ -1,14 +1,14 @@
 package slog
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
-	"time"
 
-	"github.com/evalphobia/logrus_sentry"
 	"github.com/getsentry/raven-go"
 	"github.com/muravjov/slog/base"
+	"github.com/muravjov/slog/sentry"
 	slogV2 "github.com/muravjov/slog/v2"
 	"github.com/muravjov/slog/watcher"
 	"github.com/op/go-logging"
@@ -37,6 +37,13 @@ func OpenLogOrNil(logPath string) io.Writer {
 	return logWriter
 }
 
+func openLogOrStderr(logPath string) io.Writer {
+	if logPath == "" {
+		return os.Stderr
+	}
+	return base.OpenLog(logPath)
+}
+
 func SetupLog(logPath string, dsn string) {
 	logWriter := OpenLogOrNil(logPath)
 
@@ -63,34 +70,68 @@ func SetupLogrus(logPath string, dsn string) {
 	if dsn != "" {
 		slogV2.MustSetDSNAndHandler(dsn)
 
-		// :TRICKY: with right timeout 5 sec
-		//hook, err := logrus_sentry.NewSentryHook(dsn, []logrus.Level{
-		hook, err := logrus_sentry.NewWithClientSentryHook(raven.DefaultClient, []logrus.Level{
-			logrus.PanicLevel,
-			logrus.FatalLevel,
-			logrus.ErrorLevel,
-			logrus.WarnLevel,
-		})
-		base.CheckFatal("Can't create logrus_sentry.SentryHook: ", err)
-
-		// :TODO: now Sentry errors are being logged to os.Stderr,- "Failed to fire hook: ..."
-		// log to a local file, if needed
-		hook.Timeout = time.Second * (5 + 1)
-		hook.StacktraceConfiguration.Enable = true
-
-		// :TRICKY: logrus doesn't keep original format and args; instead it does fmt.Sprintf(format, args...)
-		// several times, so we have to turn on stackraces for warnings also
-		// :TODO: fix upstream:
-		// - by correcting func (hook *SentryHook) Fire(entry *logrus.Entry)
-		// - making packet like packet := raven.NewPacket(message, &raven.Message{format, args})
-		hook.StacktraceConfiguration.Level = logrus.WarnLevel
-
-		logrus.AddHook(hook)
-
+		logrus.AddHook(NewLogrusHook(LogrusHookOptions{}))
+		// reported entries become breadcrumbs, when captured
+		logrus.AddHook(&LogrusBreadcrumbHook{levels: logrusBreadcrumbLevels})
 	}
 	watcher.StartWatcher(dsn, logPath)
 }
 
+var logrusSeverities = map[logrus.Level]raven.Severity{
+	logrus.TraceLevel: raven.DEBUG,
+	logrus.DebugLevel: raven.DEBUG,
+	logrus.InfoLevel:  raven.INFO,
+	logrus.WarnLevel:  raven.WARNING,
+	logrus.ErrorLevel: raven.ERROR,
+	logrus.FatalLevel: raven.FATAL,
+	logrus.PanicLevel: raven.FATAL,
+}
+
+// LogrusBreadcrumbHook keeps logrus records as Sentry breadcrumbs,
+// see sentry.SetBreadcrumbs()
+type LogrusBreadcrumbHook struct {
+	// nil means all levels
+	levels []logrus.Level
+}
+
+func (hook *LogrusBreadcrumbHook) Levels() []logrus.Level {
+	if hook.levels == nil {
+		return logrus.AllLevels
+	}
+	return hook.levels
+}
+
+func (hook *LogrusBreadcrumbHook) Fire(entry *logrus.Entry) error {
+	level := logrusSeverities[entry.Level]
+	if !sentry.BreadcrumbsEnabledFor(level) {
+		return nil
+	}
+
+	var data map[string]interface{}
+	if len(entry.Data) != 0 {
+		data = map[string]interface{}{}
+		for key, val := range entry.Data {
+			data[key] = fmt.Sprint(val)
+		}
+	}
+
+	sentry.AddBreadcrumb(sentry.Breadcrumb{
+		Timestamp: sentry.UnixTime(entry.Time),
+		Category:  "logrus",
+		Message:   entry.Message,
+		Level:     level,
+		Data:      data,
+	})
+	return nil
+}
+
+// WithFingerprint() makes logrus entry with explicit Sentry fingerprint, e.g.
+// slog.WithFingerprint("db", "timeout").Errorf("query failed: %s", err)
+func WithFingerprint(fingerprint ...string) *logrus.Entry {
+	// LogrusHook gets fingerprint from this field
+	return logrus.WithField("fingerprint", fingerprint)
+}
+
 func AddForceErrorOption() *string {
 	return flag.StringP("force-error", "", "no", "emulate error for logging {no, error, panic}, default = no")
 }
@@ -109,6 +150,12 @@ func RunForceErrorOption(forceError string, errorFunc func(string)) {
 
 // andStandardLog - hook https://golang.org/pkg/log calls also
 func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
+	SetupGoLoggingEx(logPath, dsn, andStandardLog, slogV2.GoLoggingOptions{})
+}
+
+// SetupGoLoggingEx() = SetupGoLogging() with per-module levels of the local log and Sentry, e.g.
+// opts.SentryLevels, _ = slogV2.ParseModuleLevels("db=ERROR,http=WARNING")
+func SetupGoLoggingEx(logPath string, dsn string, andStandardLog bool, opts slogV2.GoLoggingOptions) {
 	var logWriter io.Writer = os.Stderr
 	if logPath != "" {
 		logWriter = base.OpenLog(logPath)
@@ -118,7 +165,8 @@ func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
 	// we use go-logging formatter
 	//var flag int = log.LstdFlags
 	var flag int = 0
-	fileBackend := logging.NewLogBackend(logWriter, "", flag)
+	fileBackend := logging.AddModuleLevel(logging.NewLogBackend(logWriter, "", flag))
+	slogV2.SetModuleLevels(fileBackend, opts.FileLevels)
 
 	logBackends := []logging.Backend{
 		fileBackend,
@@ -129,7 +177,9 @@ func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
 	if withSentry {
 		slogV2.MustSetDSNAndHandler(dsn)
 
-		logBackends = append(logBackends, slogV2.NewSB())
+		sb := &slogV2.SentryBackend{}
+		sb.SetLevels(opts.SentryLevels)
+		logBackends = append(logBackends, sb)
 	}
 	watcher.StartWatcher(dsn, logPath)
 
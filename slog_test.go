@@ -2,8 +2,11 @@ package slog
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	stdslog "log/slog"
 	"net/http"
 	"os"
 	"testing"
@@ -13,9 +16,14 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/muravjov/slog/base"
 	"github.com/muravjov/slog/sentry"
+	"github.com/muravjov/slog/sentry/sentrytest"
 	slogV2 "github.com/muravjov/slog/v2"
 	"github.com/op/go-logging"
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 var RandStringBytes = base.RandStringBytes
@@ -53,6 +61,7 @@ func TestSlog(t *testing.T) {
 
 		// and without sources errors in the same functions will be aggregated
 		// :TODO: append Message interface like for CaptureMessageAndWait()
+		// use sentry.SetGroupingStrategy(sentry.GroupByFormat) to group by format string instead
 
 		logFunc := logger.Errorf
 		//logFunc := logger.Warningf
@@ -167,3 +176,132 @@ created by main.main.func1
 		//logrus.Errorf("Random text simulate: %s", RandStringBytes(8))
 	}
 }
+
+func TestSentryHandler(t *testing.T) {
+	sentrytest.Setup(t)
+
+	buf := &bytes.Buffer{}
+	logger := stdslog.New(NewSentryHandler(stdslog.NewTextHandler(buf, nil), SentryHandlerOptions{
+		Tags: map[string]string{"module": "module", "req.host": "host"},
+	})).With("module", "db")
+
+	logger.Info("connected")
+	logger.WithGroup("req").Warn("slow query", "host", "db-1", "ms", 1500)
+	logger.Error("query failed", "err", errors.New("timeout"), "table", "users")
+
+	require.Contains(t, buf.String(), "msg=connected")
+	require.Contains(t, buf.String(), "req.ms=1500")
+
+	event := sentrytest.RequireEvent(t, raven.WARNING, "^slow query$")
+	require.Equal(t, "db", event.Tags["module"])
+	require.Equal(t, "db-1", event.Tags["host"])
+	require.Equal(t, float64(1500), event.Extra["req.ms"])
+	require.Equal(t, "slow query", event.LogEntry.Message)
+	require.Equal(t, "slog_test.go", event.Extra["filename"])
+
+	event = sentrytest.RequireEvent(t, raven.ERROR, "^query failed$")
+	require.Equal(t, "users", event.Extra["table"])
+	require.Len(t, event.Exceptions, 1)
+	require.Equal(t, "timeout", event.Exceptions[0].Value)
+	frames := event.Exceptions[0].Stacktrace.Frames
+	require.Equal(t, "TestSentryHandler", frames[len(frames)-1].Function)
+
+	require.Len(t, sentrytest.Events(), 2)
+}
+
+func TestSentryHandlerZeroPC(t *testing.T) {
+	sentrytest.Setup(t)
+
+	// log/slog allows records without PC and time, see testing/slogtest
+	handler := NewSentryHandler(stdslog.NewTextHandler(&bytes.Buffer{}, nil), SentryHandlerOptions{})
+	require.NoError(t, handler.Handle(context.Background(), stdslog.NewRecord(time.Time{}, stdslog.LevelError, "no pc", 0)))
+	require.NoError(t, handler.Handle(context.Background(), stdslog.NewRecord(time.Time{}, stdslog.LevelWarn, "no pc warning", 0)))
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "^no pc$")
+	require.Nil(t, event.Stacktrace)
+	sentrytest.RequireEvent(t, raven.WARNING, "^no pc warning$")
+}
+
+func TestSetupZap(t *testing.T) {
+	SetupZap("", sentrytest.DSN)
+	sentrytest.Setup(t)
+
+	logger := zap.L().Named("db")
+	logger.Info("connected")
+	logger.Warn("slow query", zap.Int("ms", 1500))
+	logger.Error("query failed", zap.Error(errors.New("timeout")), zap.String("table", "users"))
+
+	event := sentrytest.RequireEvent(t, raven.WARNING, "^slow query$")
+	require.Equal(t, "db", event.Tags["module"])
+	require.Equal(t, float64(1500), event.Extra["ms"])
+	require.Equal(t, "slog_test.go", event.Extra["filename"])
+
+	event = sentrytest.RequireEvent(t, raven.ERROR, "^query failed$")
+	require.Equal(t, "users", event.Extra["table"])
+	require.Equal(t, "timeout", event.Exceptions[0].Value)
+	frames := event.Exceptions[0].Stacktrace.Frames
+	require.Equal(t, "TestSetupZap", frames[len(frames)-1].Function)
+
+	require.Len(t, sentrytest.Events(), 2)
+}
+
+func TestSetupZerolog(t *testing.T) {
+	SetupZerolog("", sentrytest.DSN)
+	sentrytest.Setup(t)
+
+	logger := zlog.With().Str("module", "db").Logger()
+	logger.Info().Msg("connected")
+	logger.Warn().Int("ms", 1500).Msg("slow query")
+	logger.Error().Err(errors.New("timeout")).Msg("query failed")
+
+	event := sentrytest.RequireEvent(t, raven.WARNING, "^slow query$")
+	require.Equal(t, "db", event.Tags["module"])
+	require.Equal(t, float64(1500), event.Extra["ms"])
+	require.Equal(t, "slog_test.go", event.Extra["filename"])
+
+	event = sentrytest.RequireEvent(t, raven.ERROR, "^query failed$")
+	require.Equal(t, "timeout", event.Extra["error"])
+	frames := event.Stacktrace.Frames
+	require.Equal(t, "TestSetupZerolog", frames[len(frames)-1].Function)
+
+	require.Len(t, sentrytest.Events(), 2)
+}
+
+func TestLogrusHook(t *testing.T) {
+	SetupLogrus("", sentrytest.DSN)
+	sentrytest.Setup(t)
+
+	logrus.WithError(errors.New("refused")).Error("connect failed")
+	event := sentrytest.RequireEvent(t, raven.ERROR, "connect failed")
+	require.Equal(t, "refused", event.Exceptions[0].Value)
+	frames := event.Exceptions[0].Stacktrace.Frames
+	require.Equal(t, "TestLogrusHook", frames[len(frames)-1].Function)
+
+	logrus.WithFields(logrus.Fields{"module": "db", "table": "users"}).Warn("slow table")
+	event = sentrytest.RequireEvent(t, raven.WARNING, "^slow table$")
+	require.Equal(t, "db", event.Tags["module"])
+	require.Equal(t, "users", event.Extra["table"])
+	require.Equal(t, "slog_test.go", event.Extra["filename"])
+
+	Warnf("slow query: %d ms", 1500)
+	event = sentrytest.RequireEvent(t, raven.WARNING, "^slow query: 1500 ms$")
+	require.Equal(t, "slow query: %d ms", event.LogEntry.Message)
+	require.Equal(t, "slog_test.go", event.Extra["filename"])
+
+	require.Len(t, sentrytest.Events(), 3)
+}
+
+func TestZerologWriterLevel(t *testing.T) {
+	slogV2.MustSetDSNAndHandler(sentrytest.DSN)
+	sentrytest.Setup(t)
+
+	level := zerolog.DebugLevel
+	logger := zerolog.New(NewZerologWriter(nil, ZerologWriterOptions{Level: &level}))
+	logger.Debug().Msg("debug reported")
+	sentrytest.RequireEvent(t, raven.DEBUG, "^debug reported$")
+
+	// WarnLevel by default
+	logger = zerolog.New(NewZerologWriter(nil, ZerologWriterOptions{}))
+	logger.Info().Msg("info skipped")
+	require.Len(t, sentrytest.Events(), 1)
+}
@@ -0,0 +1,189 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	stdslog "log/slog"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+	slogV2 "github.com/muravjov/slog/v2"
+	"github.com/muravjov/slog/watcher"
+)
+
+// SentryHandlerOptions configures SentryHandler
+type SentryHandlerOptions struct {
+	// records at or above it are reported, stdslog.LevelWarn if nil;
+	// less severe ones go to breadcrumbs, if they are turned on
+	Level stdslog.Leveler
+	// attribute key => tag name, other attributes go to extra;
+	// nil means {"module": "module"}, like go-logging module tag.
+	// Keys of attributes within groups are like "group.key"
+	Tags map[string]string
+	// nil means sentry.DefaultClient()
+	Client *sentry.Client
+}
+
+// SentryHandler is log/slog handler, which passes records to Inner handler and
+// reports them to Sentry. The aggregation key is the record message, because
+// variable data goes to attributes; errors among attributes are reported as exceptions
+type SentryHandler struct {
+	Inner stdslog.Handler
+
+	opts SentryHandlerOptions
+	// attributes of WithAttrs(), with group prefix
+	attrs  []stdslog.Attr
+	prefix string
+}
+
+func NewSentryHandler(inner stdslog.Handler, opts SentryHandlerOptions) *SentryHandler {
+	return &SentryHandler{
+		Inner: inner,
+		opts:  opts,
+	}
+}
+
+// SlogSeverity() maps log/slog level to Sentry one; levels above
+// stdslog.LevelError are FATAL from LevelError+4 on
+func SlogSeverity(level stdslog.Level) raven.Severity {
+	switch {
+	case level >= stdslog.LevelError+4:
+		return raven.FATAL
+	case level >= stdslog.LevelError:
+		return raven.ERROR
+	case level >= stdslog.LevelWarn:
+		return raven.WARNING
+	case level >= stdslog.LevelInfo:
+		return raven.INFO
+	}
+	return raven.DEBUG
+}
+
+func (h *SentryHandler) level() stdslog.Level {
+	if h.opts.Level == nil {
+		return stdslog.LevelWarn
+	}
+	return h.opts.Level.Level()
+}
+
+func (h *SentryHandler) Enabled(ctx context.Context, level stdslog.Level) bool {
+	return h.Inner.Enabled(ctx, level) || level >= h.level() ||
+		sentry.BreadcrumbsEnabledFor(SlogSeverity(level))
+}
+
+func (h *SentryHandler) WithAttrs(attrs []stdslog.Attr) stdslog.Handler {
+	c := *h
+	c.Inner = h.Inner.WithAttrs(attrs)
+	c.attrs = nil
+	c.attrs = append(c.attrs, h.attrs...)
+	for _, attr := range attrs {
+		c.attrs = appendAttr(c.attrs, h.prefix, attr)
+	}
+	return &c
+}
+
+func (h *SentryHandler) WithGroup(name string) stdslog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.Inner = h.Inner.WithGroup(name)
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// appendAttr() flattens groups into keys like "group.key"
+func appendAttr(attrs []stdslog.Attr, prefix string, attr stdslog.Attr) []stdslog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(stdslog.Attr{}) {
+		return attrs
+	}
+
+	if attr.Value.Kind() == stdslog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			attrs = appendAttr(attrs, prefix, a)
+		}
+		return attrs
+	}
+
+	attr.Key = prefix + attr.Key
+	return append(attrs, attr)
+}
+
+func extraValue(v stdslog.Value) interface{} {
+	switch v.Kind() {
+	case stdslog.KindString, stdslog.KindInt64, stdslog.KindUint64, stdslog.KindFloat64, stdslog.KindBool:
+		return v.Any()
+	}
+	return fmt.Sprint(v.Any())
+}
+
+func (h *SentryHandler) Handle(ctx context.Context, r stdslog.Record) error {
+	var err error
+	if h.Inner.Enabled(ctx, r.Level) {
+		err = h.Inner.Handle(ctx, r)
+	}
+
+	attrs := append([]stdslog.Attr(nil), h.attrs...)
+	r.Attrs(func(attr stdslog.Attr) bool {
+		attrs = appendAttr(attrs, h.prefix, attr)
+		return true
+	})
+
+	tagNames := h.opts.Tags
+	if tagNames == nil {
+		tagNames = defaultFieldTags
+	}
+
+	tags := map[string]string{}
+	extra := map[string]interface{}{}
+	var errArg error
+	for _, attr := range attrs {
+		if name, ok := tagNames[attr.Key]; ok {
+			tags[name] = attr.Value.String()
+			continue
+		}
+		if e, ok := attr.Value.Any().(error); ok && errArg == nil && !sentry.IsNilError(e) {
+			errArg = e
+			continue
+		}
+		extra[attr.Key] = extraValue(attr.Value)
+	}
+
+	rec := fieldsRecord{
+		Message: r.Message,
+		Level:   SlogSeverity(r.Level),
+		Time:    r.Time,
+		PC:      r.PC,
+		Tags:    tags,
+		Extra:   extra,
+		Err:     errArg,
+	}
+	if r.Level < h.level() {
+		data := map[string]interface{}{}
+		for _, attr := range attrs {
+			data[attr.Key] = attr.Value.String()
+		}
+		addFieldsBreadcrumb(rec, "slog", data)
+		return err
+	}
+
+	captureFields(ctx, h.opts.Client, rec)
+	return err
+}
+
+// SetupSlog() makes log/slog default logger write text records to logPath, stderr if empty,
+// and report warnings and errors to Sentry
+func SetupSlog(logPath string, dsn string) {
+	var handler stdslog.Handler = stdslog.NewTextHandler(openLogOrStderr(logPath), nil)
+	if dsn != "" {
+		slogV2.MustSetDSNAndHandler(dsn)
+		handler = NewSentryHandler(handler, SentryHandlerOptions{})
+	}
+	watcher.StartWatcher(dsn, logPath)
+
+	stdslog.SetDefault(stdslog.New(handler))
+}
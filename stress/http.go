@@ -65,6 +65,12 @@ type ServerHandler struct {
 	routes []*Route
 }
 
+func NewServerHandler(routes []*Route) *ServerHandler {
+	return &ServerHandler{
+		routes,
+	}
+}
+
 func (handler *ServerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		// we should process exceptions ourselves, because:
@@ -6,15 +6,11 @@ import (
 	"io/ioutil"
 
 	"github.com/BurntSushi/toml"
+	"github.com/muravjov/slog/tlsconfig"
 )
 
-type TLSConfig struct {
-	CA         string `toml:"ca"`
-	Cert       string `toml:"cert"`
-	Key        string `toml:"key"`
-	SkipVerify bool   `toml:"skip_verify"`
-	ServerName string `toml:"server_name"`
-}
+// the format is shared with Sentry transport, see sentry.SetHTTPOptions()
+type TLSConfig = tlsconfig.TLSConfig
 
 func makeTLSConfig(ca, cert, key string, isCLient bool) (tlsConfig *tls.Config) {
 	if ca != "" && cert != "" && key != "" {
@@ -25,19 +25,22 @@ func DecodeCheckJSONBody(w http.ResponseWriter, r *http.Request, v interface{})
 type StrDict map[string]interface{}
 
 func ServeDummyHTTP(targetUrl string, route *Route, serverTLSConfigFname string) {
+	ServeDummyHTTPEx(targetUrl, Routes{route}, serverTLSConfigFname)
+}
+
+// ServeDummyHTTPEx() = ServeDummyHTTP() with several routes
+func ServeDummyHTTPEx(targetUrl string, routes Routes, serverTLSConfigFname string) {
 	uri, err := url.Parse(targetUrl)
 	CheckError(err)
 
 	serveData := NewServeData("n/a")
 	//makeRoute := GenerateMakeRoute(serveData)
 
-	serveData.routeList = append(serveData.routeList, RouteHelp{
-		Pattern: route.pattern,
-		Help:    "stress handler",
-	})
-
-	routes := Routes{
-		route,
+	for _, route := range routes {
+		serveData.routeList = append(serveData.routeList, RouteHelp{
+			Pattern: route.pattern,
+			Help:    "stress handler",
+		})
 	}
 
 	var tlsConfig *tls.Config
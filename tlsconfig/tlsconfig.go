@@ -0,0 +1,86 @@
+/*
+Package tlsconfig loads client TLS settings from TOML files like
+
+	ca = "ca.pem"
+	cert = "client.pem"
+	key = "client.key"
+	skip_verify = false
+	server_name = "sentry.internal"
+
+stress and sentry packages share the format.
+*/
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/BurntSushi/toml"
+)
+
+type TLSConfig struct {
+	CA         string `toml:"ca"`
+	Cert       string `toml:"cert"`
+	Key        string `toml:"key"`
+	SkipVerify bool   `toml:"skip_verify"`
+	ServerName string `toml:"server_name"`
+}
+
+// Load() reads TOML file
+func Load(fname string) (*TLSConfig, error) {
+	contents, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("error reading TLS config: %s", err)
+	}
+
+	res := &TLSConfig{}
+	if _, err := toml.Decode(string(contents), res); err != nil {
+		return nil, fmt.Errorf("error parsing TLS config %s: %s", fname, err)
+	}
+	return res, nil
+}
+
+// Client() makes client config: CA is trusted in addition to system ones,
+// cert and key are a client certificate, for mutual TLS
+func (t *TLSConfig) Client() (*tls.Config, error) {
+	res := &tls.Config{
+		InsecureSkipVerify: t.SkipVerify,
+		ServerName:         t.ServerName,
+	}
+
+	if t.CA != "" {
+		caCert, err := ioutil.ReadFile(t.CA)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates in %s", t.CA)
+		}
+		res.RootCAs = pool
+	}
+
+	if t.Cert != "" || t.Key != "" {
+		cert, err := tls.LoadX509KeyPair(t.Cert, t.Key)
+		if err != nil {
+			return nil, err
+		}
+		res.Certificates = []tls.Certificate{cert}
+	}
+	return res, nil
+}
+
+// NewClientConfig() = Load() + Client()
+func NewClientConfig(fname string) (*tls.Config, error) {
+	t, err := Load(fname)
+	if err != nil {
+		return nil, err
+	}
+	return t.Client()
+}
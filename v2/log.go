@@ -2,10 +2,12 @@ package slog
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -16,8 +18,81 @@ import (
 )
 
 type SentryBackend struct {
-	// we use DefaultClient and global raven.SetDSN()
-	//Client *raven.Client
+	// go-logging module => client, to report subsystems to different Sentry projects;
+	// other modules go to DefaultClient
+	Clients map[string]*sentry.Client
+	// nil means sentry.DefaultClient(), i.e. global raven.SetDSN()
+	DefaultClient *sentry.Client
+
+	levelsMutex sync.RWMutex
+	// go-logging module => the least severe reported level, "" is the default module;
+	// WARNING if not set
+	levels map[string]logging.Level
+}
+
+func (l *SentryBackend) client(module string) *sentry.Client {
+	if c, ok := l.Clients[module]; ok {
+		return c
+	}
+	if l.DefaultClient != nil {
+		return l.DefaultClient
+	}
+	return sentry.DefaultClient()
+}
+
+// reportedLevel() is the threshold of the module: less severe records go to breadcrumbs
+func (l *SentryBackend) reportedLevel(module string) logging.Level {
+	l.levelsMutex.RLock()
+	defer l.levelsMutex.RUnlock()
+	if level, ok := l.levels[module]; ok {
+		return level
+	}
+	if level, ok := l.levels[""]; ok {
+		return level
+	}
+	return logging.WARNING
+}
+
+// SetLevels() replaces the level table, e.g. ParseModuleLevels("db=ERROR,http=WARNING")
+func (l *SentryBackend) SetLevels(levels map[string]logging.Level) {
+	table := map[string]logging.Level{}
+	for module, level := range levels {
+		table[module] = level
+	}
+
+	l.levelsMutex.Lock()
+	defer l.levelsMutex.Unlock()
+	l.levels = table
+}
+
+// ParseModuleLevels() parses spec like "db=ERROR,http=WARNING"; a level without
+// module, e.g. "ERROR,db=DEBUG", is the default one
+func ParseModuleLevels(spec string) (map[string]logging.Level, error) {
+	res := map[string]logging.Level{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		module, name := "", item
+		if idx := strings.LastIndex(item, "="); idx != -1 {
+			module, name = strings.TrimSpace(item[:idx]), strings.TrimSpace(item[idx+1:])
+		}
+		level, err := logging.LogLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("bad level %q of module %q", name, module)
+		}
+		res[module] = level
+	}
+	return res, nil
+}
+
+// SetModuleLevels() sets levels of go-logging backend, e.g. the file one
+func SetModuleLevels(backend logging.LeveledBackend, levels map[string]logging.Level) {
+	for module, level := range levels {
+		backend.SetLevel(level, module)
+	}
 }
 
 type LoggingRecord struct {
@@ -35,6 +110,24 @@ type LoggingRecord struct {
 	formatted string
 }
 
+var fingerprintsMutex sync.RWMutex
+var moduleFingerprints = map[string][]string{}
+
+// SetModuleFingerprint() makes all Sentry events of go-logging module
+// to be grouped by fingerprint, e.g.
+// SetModuleFingerprint("db", "{{ default }}", "db") to separate them from others
+func SetModuleFingerprint(module string, fingerprint ...string) {
+	fingerprintsMutex.Lock()
+	defer fingerprintsMutex.Unlock()
+	moduleFingerprints[module] = fingerprint
+}
+
+func moduleFingerprint(module string) []string {
+	fingerprintsMutex.RLock()
+	defer fingerprintsMutex.RUnlock()
+	return moduleFingerprints[module]
+}
+
 func Record2Level(rec *logging.Record) raven.Severity {
 	res := raven.ERROR
 	switch rec.Level {
@@ -47,8 +140,38 @@ func Record2Level(rec *logging.Record) raven.Severity {
 	return res
 }
 
+func Level2Severity(level logging.Level) raven.Severity {
+	switch level {
+	case logging.NOTICE, logging.INFO:
+		return raven.INFO
+	case logging.DEBUG:
+		return raven.DEBUG
+	}
+	return Record2Level(&logging.Record{Level: level})
+}
+
+// the first error among logging call args, it is reported as Sentry exception
+func errorArg(args []interface{}) error {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok && !sentry.IsNilError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 func (l *SentryBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
-	if (level <= logging.WARNING) && (rec.Module != "sentry.errors") {
+	if level > l.reportedLevel(rec.Module) {
+		sentry.AddBreadcrumb(sentry.Breadcrumb{
+			Timestamp: sentry.UnixTime(rec.Time),
+			Category:  rec.Module,
+			Message:   rec.Message(),
+			Level:     Level2Severity(level),
+		})
+		return nil
+	}
+
+	if rec.Module != "sentry.errors" {
 		cd := calldepth + 2
 
 		//message := rec.Formatted(calldepth+2)
@@ -66,54 +189,109 @@ func (l *SentryBackend) Log(level logging.Level, calldepth int, rec *logging.Rec
 
 		isWarning := level == logging.WARNING
 
-		if isWarning {
-			// * aggregation key
+		// * aggregation key
 
-			// .fmt is private, f*ck
-			//key := rec.fmt
+		// .fmt is private, f*ck
+		//key := rec.fmt
 
-			key := message
-			lRec := (*LoggingRecord)(unsafe.Pointer(rec))
-			if lRec.fmt != nil {
-				key = *lRec.fmt
-			}
+		key := message
+		lRec := (*LoggingRecord)(unsafe.Pointer(rec))
+		if lRec.fmt != nil {
+			key = *lRec.fmt
+		}
 
-			sentry.CaptureMessageAndWait(message, tags, cd, &raven.Message{
+		grouping := sentry.Grouping{
+			Format:      key,
+			Fingerprint: moduleFingerprint(rec.Module),
+		}
+
+		client := l.client(rec.Module)
+		if err := errorArg(rec.Args); err != nil {
+			packet := sentry.NewExceptionPacket(err, message, tags, cd, Level2Severity(level), grouping)
+			packet.Interfaces = append(packet.Interfaces, &raven.Message{
 				Message: key,
 				Params:  rec.Args,
 			})
+			client.CaptureAndWait(packet, tags)
+		} else if isWarning {
+			client.CaptureMessageAndWaitEx(message, tags, cd, &raven.Message{
+				Message: key,
+				Params:  rec.Args,
+			}, grouping)
 		} else {
-			sentry.CaptureErrorAndWait(message, tags, cd, Record2Level(rec))
+			// NOTICE and INFO are reported as is, if the module level is lowered
+			client.CaptureErrorAndWaitEx(message, tags, cd, Level2Severity(level), grouping)
 		}
 	}
 	return nil
 }
 
 //
-// :TRICKY: we want LeveledBackend interface to force level WARNING
+// :TRICKY: LeveledBackend levels are the reported ones, WARNING by default, see SetLevels();
+// less severe records go to breadcrumbs, if they are turned on.
+// SetLevel() is no-op: logging.SetLevel() sets levels of all backends, and it's meant
+// for the local log, not to flood Sentry
 //
 
 func (l *SentryBackend) GetLevel(module string) logging.Level {
-	return logging.WARNING
+	reported := l.reportedLevel(module)
+	for level := logging.DEBUG; level > reported; level-- {
+		if sentry.BreadcrumbsEnabledFor(Level2Severity(level)) {
+			return level
+		}
+	}
+	return reported
 }
 
 func (l *SentryBackend) SetLevel(level logging.Level, module string) {
 }
 
 func (l *SentryBackend) IsEnabledFor(level logging.Level, module string) bool {
-	return level <= logging.WARNING
+	return level <= l.reportedLevel(module) || sentry.BreadcrumbsEnabledFor(Level2Severity(level))
 }
 
 func NewSB() logging.LeveledBackend {
 	return &SentryBackend{}
 }
 
+// MustNewSBWithDSNs() makes backend, which sends events of the modules to their own DSNs,
+// e.g. {"db": "https://key@sentry.io/2"}; other modules go to the global DSN
+func MustNewSBWithDSNs(dsns map[string]string) logging.LeveledBackend {
+	clients := map[string]*sentry.Client{}
+	for module, dsn := range dsns {
+		client, err := sentry.NewClient(dsn, nil, nil)
+		if err != nil {
+			log.Fatalf("Bad Sentry DSN '%s' for module %s: %s", dsn, module, err)
+		}
+		clients[module] = client
+	}
+	return &SentryBackend{
+		Clients: clients,
+	}
+}
+
 //
 // log
 //
 
 type SentryLog struct {
 	Writer io.Writer
+	// logger, which writes to it, to split lines according to its flags and prefix;
+	// nil means the standard one
+	Logger *log.Logger
+
+	StdLoggerOptions
+}
+
+// Sentry settings of a standard logger, see NewStdLogger()
+type StdLoggerOptions struct {
+	Tags map[string]string
+	// of all lines; detected if empty, see SetStdLevels()
+	Level raven.Severity
+	// less severe lines are not reported; StdLevelOptions.MinLevel if empty
+	MinLevel raven.Severity
+	// nil means sentry.DefaultClient()
+	Client *sentry.Client
 }
 
 // to emulate standard logger
@@ -128,18 +306,78 @@ func SkipSpace(s string) string {
 	return s
 }
 
+// ParseLine() splits line written by the logger
+func (w *SentryLog) ParseLine(s string) LogLine {
+	// :TRICKY: log.Logger calls Write() under its mutex; since Go 1.21, see go.mod,
+	// Flags() and Prefix() are atomic and don't take it, so they are read live
+	if w.Logger != nil {
+		return ParseLogLine(s, w.Logger.Flags(), w.Logger.Prefix())
+	}
+	return ParseLogLine(s, log.Flags(), log.Prefix())
+}
+
 // io.Writer interface for log
 func (w *SentryLog) Write(p []byte) (n int, err error) {
 	n, err = w.Writer.Write(p)
 
-	s := string(p)
-	// because of log.LstdFlags we need to skip 2 spaces
-	s = SkipSpace(SkipSpace(s))
-	sentry.CaptureErrorAndWait(s, nil, 4, raven.ERROR)
+	line := w.ParseLine(string(p))
+
+	pc, fatal := stdLogCaller(&line)
+	level := w.Level
+	if level == "" {
+		level = DetectLevel(line.Message)
+	}
+	if fatal {
+		level = raven.FATAL
+	}
+	if !reportedStdLevel(level, w.MinLevel) {
+		sentry.AddBreadcrumb(sentry.Breadcrumb{
+			Timestamp: sentry.UnixTime(time.Now()),
+			Category:  "log",
+			Message:   line.Message,
+			Level:     level,
+		})
+		return n, err
+	}
+
+	packet := sentry.NewErrorPacketAt(line.Message, w.Tags, pc, level, sentry.Grouping{})
+	for key, val := range line.extra() {
+		packet.Extra[key] = val
+	}
+	if !line.Time.IsZero() {
+		packet.Timestamp = raven.Timestamp(line.Time)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = sentry.DefaultClient()
+	}
+	client.CaptureAndWait(packet, w.Tags)
 
 	return n, err
 }
 
+// NewStdLogger() makes *log.Logger, which writes to w, stderr if nil, and reports
+// to Sentry with its own tags and level, e.g. for http.Server.ErrorLog:
+//
+//	ErrorLog: NewStdLogger(logWriter, "http: ", log.LstdFlags, StdLoggerOptions{
+//		Tags:  map[string]string{"module": "http"},
+//		Level: raven.WARNING,
+//	})
+func NewStdLogger(w io.Writer, prefix string, flag int, opts StdLoggerOptions) *log.Logger {
+	if w == nil {
+		w = os.Stderr
+	}
+
+	sl := &SentryLog{
+		Writer:           w,
+		StdLoggerOptions: opts,
+	}
+	logger := log.New(sl, prefix, flag)
+	sl.Logger = logger
+	return logger
+}
+
 func HookStandardLog(w io.Writer) {
 	if w == nil {
 		w = os.Stderr
@@ -174,8 +412,21 @@ func RedirectStandardLog(logWriter io.Writer, withSentry bool) {
 	}
 }
 
+// GoLoggingOptions are per-module levels of SetupGoLoggingEx(), see ParseModuleLevels()
+type GoLoggingOptions struct {
+	// of the local log, DEBUG if not set
+	FileLevels map[string]logging.Level
+	// of Sentry backend, see SentryBackend.SetLevels()
+	SentryLevels map[string]logging.Level
+}
+
 // andStandardLog - hook https://golang.org/pkg/log calls also
 func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
+	SetupGoLoggingEx(logPath, dsn, andStandardLog, GoLoggingOptions{})
+}
+
+// SetupGoLoggingEx() = SetupGoLogging() with per-module levels
+func SetupGoLoggingEx(logPath string, dsn string, andStandardLog bool, opts GoLoggingOptions) {
 	var logWriter io.Writer = os.Stderr
 	if logPath != "" {
 		logWriter = base.OpenLog(logPath)
@@ -185,7 +436,8 @@ func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
 	// we use go-logging formatter
 	//var flag int = log.LstdFlags
 	var flag int = 0
-	fileBackend := logging.NewLogBackend(logWriter, "", flag)
+	fileBackend := logging.AddModuleLevel(logging.NewLogBackend(logWriter, "", flag))
+	SetModuleLevels(fileBackend, opts.FileLevels)
 
 	logBackends := []logging.Backend{
 		fileBackend,
@@ -196,7 +448,9 @@ func SetupGoLogging(logPath string, dsn string, andStandardLog bool) {
 	if withSentry {
 		MustSetDSNAndHandler(dsn)
 
-		logBackends = append(logBackends, NewSB())
+		sb := &SentryBackend{}
+		sb.SetLevels(opts.SentryLevels)
+		logBackends = append(logBackends, sb)
 	}
 
 	logging.SetBackend(logBackends...)
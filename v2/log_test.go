@@ -1,13 +1,21 @@
 package slog
 
 import (
+	"bytes"
+	"io/ioutil"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"testing"
 	"time"
 
+	raven "github.com/getsentry/raven-go"
 	"github.com/muravjov/slog/base"
+	"github.com/muravjov/slog/sentry"
+	"github.com/muravjov/slog/sentry/sentrytest"
 	logging "github.com/op/go-logging"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSlog(t *testing.T) {
@@ -29,3 +37,192 @@ func TestSlog(t *testing.T) {
 	}
 
 }
+
+func TestParseLogLine(t *testing.T) {
+	for _, c := range []struct {
+		flags  int
+		prefix string
+	}{
+		{0, ""},
+		{log.LstdFlags, ""},
+		{log.LstdFlags | log.Lshortfile, ""},
+		{log.Ldate | log.Lmicroseconds | log.LUTC | log.Llongfile, ""},
+		{log.Ltime, "[db] "},
+		{log.LstdFlags | log.Lshortfile | log.Lmsgprefix, "db: "},
+		{log.Lmsgprefix, "db: "},
+	} {
+		buf := &bytes.Buffer{}
+		logger := log.New(buf, c.prefix, c.flags)
+		logger.Printf("query failed: %s", "a: b")
+
+		line := ParseLogLine(buf.String(), c.flags, c.prefix)
+		require.Equal(t, "query failed: a: b", line.Message, "flags %d, line %q", c.flags, buf.String())
+
+		if c.flags&(log.Ldate|log.Ltime) != 0 {
+			require.WithinDuration(t, time.Now(), line.Time, time.Second*2)
+		} else {
+			require.True(t, line.Time.IsZero())
+		}
+
+		if c.flags&(log.Lshortfile|log.Llongfile) != 0 {
+			require.Equal(t, "log_test.go", path.Base(line.File))
+			require.NotZero(t, line.Line)
+		} else {
+			require.Equal(t, "", line.File)
+		}
+	}
+}
+
+func TestParseModuleLevels(t *testing.T) {
+	levels, err := ParseModuleLevels("WARNING, db=ERROR,http=debug")
+	require.NoError(t, err)
+	require.Equal(t, map[string]logging.Level{
+		"":     logging.WARNING,
+		"db":   logging.ERROR,
+		"http": logging.DEBUG,
+	}, levels)
+
+	_, err = ParseModuleLevels("db=LOUD")
+	require.Error(t, err)
+
+	sb := &SentryBackend{}
+	sb.SetLevels(levels)
+	require.True(t, sb.IsEnabledFor(logging.ERROR, "db"))
+	require.False(t, sb.IsEnabledFor(logging.WARNING, "db"))
+	require.True(t, sb.IsEnabledFor(logging.WARNING, "other"))
+	require.False(t, sb.IsEnabledFor(logging.NOTICE, "other"))
+
+	// logging.SetLevel() is for the local log
+	sb.SetLevel(logging.DEBUG, "")
+	require.False(t, sb.IsEnabledFor(logging.NOTICE, "other"))
+}
+
+func TestStandardLogFlags(t *testing.T) {
+	MustSetDSNAndHandler(sentrytest.DSN)
+	HookStandardLog(&bytes.Buffer{})
+	sentrytest.Setup(t)
+
+	flags, prefix := log.Flags(), log.Prefix()
+	defer func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}()
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile | log.Lmsgprefix)
+	log.SetPrefix("app: ")
+
+	log.Printf("disk full: %d%%", 99)
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "^disk full: 99%$")
+	require.Equal(t, "log_test.go", event.Extra["filename"])
+	require.NotZero(t, event.Extra["lineno"])
+	frames := event.Stacktrace.Frames
+	require.Equal(t, "TestStandardLogFlags", frames[len(frames)-1].Function)
+}
+
+func TestStandardLogLevels(t *testing.T) {
+	MustSetDSNAndHandler(sentrytest.DSN)
+	HookStandardLog(&bytes.Buffer{})
+	sentrytest.Setup(t)
+
+	SetStdLevels(&StdLevelOptions{Rules: DefaultLevelRules()})
+	defer SetStdLevels(nil)
+	sentry.SetBreadcrumbs(sentry.BreadcrumbsOptions{Size: 10, MinLevel: raven.DEBUG})
+	defer sentry.SetBreadcrumbs(sentry.BreadcrumbsOptions{})
+
+	log.Printf("[INFO] listening on :8080")
+	log.Printf("warning: disk is 90%% full")
+	log.Printf("information schema is broken")
+
+	event := sentrytest.RequireEvent(t, raven.WARNING, "^warning: disk")
+	require.Len(t, event.Breadcrumbs, 1)
+	require.Equal(t, "[INFO] listening on :8080", event.Breadcrumbs[0].Message)
+	sentrytest.RequireEvent(t, raven.ERROR, "^information schema")
+	require.Len(t, sentrytest.Events(), 2)
+
+	func() {
+		defer func() { recover() }()
+		log.Panicf("no config")
+	}()
+	sentrytest.RequireEvent(t, raven.FATAL, "^no config$")
+}
+
+func TestStdLogger(t *testing.T) {
+	sentrytest.Setup(t)
+
+	buf := &bytes.Buffer{}
+	logger := NewStdLogger(buf, "http: ", log.Lshortfile|log.Lmsgprefix, StdLoggerOptions{
+		Tags:  map[string]string{"module": "http"},
+		Level: raven.WARNING,
+	})
+	logger.Printf("TLS handshake error from %s", "10.0.0.1")
+	require.Contains(t, buf.String(), "log_test.go")
+
+	event := sentrytest.RequireEvent(t, raven.WARNING, "^TLS handshake error from 10.0.0.1$")
+	require.Equal(t, "http", event.Tags["module"])
+	require.Equal(t, "log_test.go", event.Extra["filename"])
+	frames := event.Stacktrace.Frames
+	require.Equal(t, "TestStdLogger", frames[len(frames)-1].Function)
+
+	// wrappers pass calldepth to log.Output()
+	sentrytest.Recorder().Reset()
+	func() {
+		logger.Output(2, "via wrapper")
+	}()
+	event = sentrytest.RequireEvent(t, raven.WARNING, "^via wrapper$")
+	frames = event.Stacktrace.Frames
+	require.Equal(t, "TestStdLogger", frames[len(frames)-1].Function)
+}
+
+func TestModuleLevels(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	var opts GoLoggingOptions
+	var err error
+	opts.FileLevels, err = ParseModuleLevels("db=WARNING")
+	require.NoError(t, err)
+	opts.SentryLevels, err = ParseModuleLevels("db=ERROR, audit=INFO")
+	require.NoError(t, err)
+
+	SetupGoLoggingEx(logPath, sentrytest.DSN, false, opts)
+	sentrytest.Setup(t)
+
+	db := logging.MustGetLogger("db")
+	db.Warningf("slow query: %d ms", 1500)
+	db.Infof("connected")
+	db.Errorf("query failed")
+	logging.MustGetLogger("audit").Infof("user %s logged in", "bob")
+	logging.MustGetLogger("http").Warningf("bad request")
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "query failed")
+	require.Equal(t, "db", event.Tags["module"])
+	event = sentrytest.RequireEvent(t, raven.INFO, "user bob logged in")
+	require.Equal(t, "audit", event.Tags["module"])
+	sentrytest.RequireEvent(t, raven.WARNING, "bad request")
+	require.Len(t, sentrytest.Events(), 3)
+
+	content, err := ioutil.ReadFile(logPath)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "slow query: 1500 ms")
+	require.NotContains(t, string(content), "connected")
+	require.Contains(t, string(content), "user bob logged in")
+
+	// tunes the local log only
+	logging.SetLevel(logging.INFO, "")
+	logging.MustGetLogger("chatty").Infof("just chatter %d", 1)
+	require.Len(t, sentrytest.Events(), 3)
+}
+
+type nilError struct{ text string }
+
+func (e *nilError) Error() string { return e.text }
+
+func TestNilErrorArg(t *testing.T) {
+	SetupGoLogging("", sentrytest.DSN, false)
+	sentrytest.Setup(t)
+
+	var err *nilError
+	logging.MustGetLogger("db").Errorf("query failed: %v", err)
+
+	event := sentrytest.RequireEvent(t, raven.ERROR, "^query failed: <nil>$")
+	require.Len(t, event.Exceptions, 0)
+	require.NotNil(t, event.Stacktrace)
+}
@@ -0,0 +1,216 @@
+package slog
+
+import (
+	"log"
+	"path"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	raven "github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+)
+
+// LogLine is a line of standard logger split according to its flags and prefix
+type LogLine struct {
+	// zero if neither log.Ldate nor log.Ltime
+	Time time.Time
+	// of log.Lshortfile or log.Llongfile
+	File string
+	Line int
+
+	Message string
+}
+
+// :TRICKY: Llongfile path may contain ": " too, so the line number is the anchor
+var fileLineRe = regexp.MustCompile(`^(.*?):(\d+): `)
+
+// ParseLogLine() undoes log.Logger header, see log.formatHeader()
+func ParseLogLine(s string, flags int, prefix string) LogLine {
+	var res LogLine
+	s = strings.TrimSuffix(s, "\n")
+
+	if flags&log.Lmsgprefix == 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+
+	var layout string
+	if flags&log.Ldate != 0 {
+		layout = "2006/01/02 "
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		layout += "15:04:05"
+		if flags&log.Lmicroseconds != 0 {
+			layout += ".000000"
+		}
+		layout += " "
+	}
+	if layout != "" && len(s) >= len(layout) {
+		loc := time.Local
+		if flags&log.LUTC != 0 {
+			loc = time.UTC
+		}
+		if t, err := time.ParseInLocation(layout, s[:len(layout)], loc); err == nil {
+			// time without date is of today
+			if flags&log.Ldate == 0 {
+				now := time.Now().In(loc)
+				t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+			}
+			res.Time = t
+			s = s[len(layout):]
+		}
+	}
+
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if m := fileLineRe.FindStringSubmatch(s); m != nil {
+			res.File = m[1]
+			res.Line, _ = strconv.Atoi(m[2])
+			s = s[len(m[0]):]
+		}
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+
+	res.Message = s
+	return res
+}
+
+// extra of Sentry event, like sentry.NewMessagePacket() makes
+func (l *LogLine) extra() map[string]interface{} {
+	if l.File == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"filename": path.Base(l.File),
+		"lineno":   l.Line,
+		"pathname": l.File,
+	}
+}
+
+// LevelRule gives Level to standard log lines matching Pattern
+type LevelRule struct {
+	Pattern *regexp.Regexp
+	Level   raven.Severity
+}
+
+// DefaultLevelRules() detects markers like "[WARN]", "warning:" and "INFO"
+// at the start of message
+func DefaultLevelRules() []LevelRule {
+	rule := func(markers string, level raven.Severity) LevelRule {
+		return LevelRule{
+			Pattern: regexp.MustCompile(`(?i)^\[?(` + markers + `)\b\]?:?`),
+			Level:   level,
+		}
+	}
+	return []LevelRule{
+		rule("debug|trace", raven.DEBUG),
+		rule("info|notice", raven.INFO),
+		rule("warn|warning", raven.WARNING),
+		rule("error|err", raven.ERROR),
+		rule("fatal|critical|crit|panic", raven.FATAL),
+	}
+}
+
+// StdLevelOptions configures levels of standard log lines, see SetStdLevels()
+type StdLevelOptions struct {
+	// the first matching rule wins
+	Rules []LevelRule
+	// of lines matching no rule, raven.ERROR if empty
+	Default raven.Severity
+	// less severe lines go to the local log only, and to breadcrumbs, if they are
+	// turned on; raven.WARNING if empty
+	MinLevel raven.Severity
+}
+
+var stdLevels *StdLevelOptions
+
+// SetStdLevels() turns on level detection of standard log lines, nil turns it off:
+// then every line is ERROR, like before. log.Fatal*() and log.Panic*() lines are FATAL anyway
+func SetStdLevels(opts *StdLevelOptions) {
+	if opts == nil {
+		stdLevels = nil
+		return
+	}
+	c := *opts
+	if c.Default == "" {
+		c.Default = raven.ERROR
+	}
+	if c.MinLevel == "" {
+		c.MinLevel = raven.WARNING
+	}
+	stdLevels = &c
+}
+
+// GetStdLevels() returns options set by SetStdLevels()
+func GetStdLevels() *StdLevelOptions {
+	return stdLevels
+}
+
+// DetectLevel() returns level of the message according to SetStdLevels()
+func DetectLevel(message string) raven.Severity {
+	opts := stdLevels
+	if opts == nil {
+		return raven.ERROR
+	}
+	for _, rule := range opts.Rules {
+		if rule.Pattern.MatchString(message) {
+			return rule.Level
+		}
+	}
+	return opts.Default
+}
+
+func reportedStdLevel(level raven.Severity, minLevel raven.Severity) bool {
+	if minLevel == "" {
+		opts := stdLevels
+		if opts == nil {
+			return true
+		}
+		minLevel = opts.MinLevel
+	}
+	return sentry.SeverityRank(level) >= sentry.SeverityRank(minLevel)
+}
+
+var fatalFuncs = []string{"log.Fatal", "log.Panic", "log.(*Logger).Fatal", "log.(*Logger).Panic"}
+
+// stdLogCaller() returns the call site of log.Printf() and friends, i.e. the first
+// caller out of log package, and whether it's log.Fatal*() or log.Panic*(), then
+// the process is going to exit. Unlike fixed calldepth, it works for log.Output() too:
+// if the line has file:line of the caller, the frame of it is the call site
+func stdLogCaller(line *LogLine) (pc uintptr, fatal bool) {
+	pcs := make([]uintptr, 32)
+	// runtime.Callers() <- stdLogCaller() <- SentryLog.Write() <- log package
+	pcs = pcs[:runtime.Callers(3, pcs)]
+
+	for _, p := range pcs {
+		frames := runtime.CallersFrames([]uintptr{p})
+		for {
+			fr, more := frames.Next()
+			if pc == 0 {
+				for _, prefix := range fatalFuncs {
+					if strings.HasPrefix(fr.Function, prefix) {
+						fatal = true
+					}
+				}
+				// a caller may be inlined with log code
+				if !strings.HasPrefix(fr.Function, "log.") {
+					pc = p
+					if line.File == "" {
+						return pc, fatal
+					}
+				}
+			}
+			if pc != 0 && fr.Line == line.Line && path.Base(fr.File) == path.Base(line.File) {
+				return p, fatal
+			}
+			if !more {
+				break
+			}
+		}
+	}
+	return pc, fatal
+}
@@ -4,6 +4,7 @@ Package watcher implements StartWatcher() function to catch Golang panics with a
 package watcher
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -28,6 +29,32 @@ import (
 // 	}
 // }
 
+// watchee settings, which watcher process inherits via environment
+type watcherOptions struct {
+	Spool sentry.SpoolOptions
+	// post-mortems contain the whole stderr of watchee
+	Scrub *sentry.ScrubOptions
+	InApp sentry.InAppOptions
+	HTTP  *sentry.HTTPOptions
+
+	// post-mortems are stamped with the same values as events of watchee
+	Release     string
+	Environment string
+	ServerName  string
+}
+
+const optionsEnv = "_SLOG_WATCHER_OPTIONS"
+
+func loadOptions() watcherOptions {
+	var opts watcherOptions
+	if s := os.Getenv(optionsEnv); s != "" {
+		if err := json.Unmarshal([]byte(s), &opts); err != nil {
+			log.Printf("Bad %s: %s", optionsEnv, err)
+		}
+	}
+	return opts
+}
+
 func WatcheePid() int {
 	return os.Getppid()
 }
@@ -54,7 +81,7 @@ func init() {
 				if e == syscall.ESRCH {
 					log.Println("Somehow watchee has already finished")
 				} else {
-					log.Fatalln("Can't signal to watchee: %s", e)
+					log.Fatalf("Can't signal to watchee: %s", e)
 				}
 			}
 
@@ -64,12 +91,31 @@ func init() {
 		}()
 
 		if dsn != "" {
+			opts := loadOptions()
+			// the transport is made by MustSetDSN()
+			if err := sentry.SetHTTPOptions(opts.HTTP); err != nil {
+				log.Printf("Can't set Sentry HTTP options: %s", err)
+			}
+
 			sentry.MustSetDSN(dsn)
 			// no go-logging dep
 			//SetupSentryLogger()
 			sentry.SetSEH(func(err error) {
 				log.Print(err)
 			})
+
+			// :TRICKY: after MustSetDSN(), so that watcher doesn't replay the spool:
+			// it's the watchee' job, watcher is to save post-mortem only
+			if err := sentry.SetSpool(opts.Spool); err != nil {
+				log.Printf("Can't set Sentry spool: %s", err)
+			}
+			if err := sentry.SetScrubber(opts.Scrub); err != nil {
+				log.Printf("Can't set Sentry scrubber: %s", err)
+			}
+			sentry.SetInApp(opts.InApp)
+			sentry.SetRelease(opts.Release)
+			sentry.SetEnvironment(opts.Environment)
+			sentry.SetServerName(opts.ServerName)
 		}
 
 		s := fmt.Sprintf("Go watcher for pid: %d", watcheePid)
@@ -88,6 +134,12 @@ func init() {
 // Starts a watchdog process to catch panics and to store them into file errFileName and
 // Sentry
 func StartWatcher(dsn string, errFileName string) {
+	// :TRICKY: registered transports, like sentrytest one, live in memory of watchee
+	// and can't get post-mortems from another process
+	if d, err := sentry.ParseDSN(dsn); err == nil && d.Transport != "" {
+		return
+	}
+
 	cx, err := osext.Executable()
 	base.CheckFatal("osext.Executable(): %s", err)
 
@@ -95,6 +147,19 @@ func StartWatcher(dsn string, errFileName string) {
 	env := os.Environ()
 	env = append(env, mark)
 
+	opts, err := json.Marshal(watcherOptions{
+		Spool: sentry.GetSpool(),
+		Scrub: sentry.GetScrubber(),
+		InApp: sentry.GetInApp(),
+		HTTP:  sentry.GetHTTPOptions(),
+
+		Release:     sentry.Release(),
+		Environment: sentry.Environment(),
+		ServerName:  sentry.ServerName(),
+	})
+	base.CheckFatal("Can't marshal watcher options: %s", err)
+	env = append(env, fmt.Sprintf("%s=%s", optionsEnv, opts))
+
 	var errFile *os.File
 	var logFile *os.File
 	if errFileName == "" {
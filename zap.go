@@ -0,0 +1,150 @@
+package slog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+	slogV2 "github.com/muravjov/slog/v2"
+	"github.com/muravjov/slog/watcher"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ZapCoreOptions configures ZapCore
+type ZapCoreOptions struct {
+	// entries at or above it are reported, zapcore.WarnLevel if nil;
+	// less severe ones go to breadcrumbs, if they are turned on
+	Level zapcore.LevelEnabler
+	// field key => tag name, other fields go to extra; nil means {"module": "module"}.
+	// Logger name, see zap.Logger.Named(), is "module" tag too
+	Tags map[string]string
+	// nil means sentry.DefaultClient()
+	Client *sentry.Client
+}
+
+// ZapCore reports zap entries to Sentry, use it with zapcore.NewTee();
+// the call site is taken from the entry, if the logger has zap.AddCaller()
+type ZapCore struct {
+	opts   ZapCoreOptions
+	fields []zapcore.Field
+}
+
+func NewZapCore(opts ZapCoreOptions) *ZapCore {
+	return &ZapCore{
+		opts: opts,
+	}
+}
+
+var zapSeverities = map[zapcore.Level]raven.Severity{
+	zapcore.DebugLevel:  raven.DEBUG,
+	zapcore.InfoLevel:   raven.INFO,
+	zapcore.WarnLevel:   raven.WARNING,
+	zapcore.ErrorLevel:  raven.ERROR,
+	zapcore.DPanicLevel: raven.FATAL,
+	zapcore.PanicLevel:  raven.FATAL,
+	zapcore.FatalLevel:  raven.FATAL,
+}
+
+func (c *ZapCore) reported(level zapcore.Level) bool {
+	if c.opts.Level == nil {
+		return level >= zapcore.WarnLevel
+	}
+	return c.opts.Level.Enabled(level)
+}
+
+func (c *ZapCore) Enabled(level zapcore.Level) bool {
+	return c.reported(level) || sentry.BreadcrumbsEnabledFor(zapSeverities[level])
+}
+
+func (c *ZapCore) With(fields []zapcore.Field) zapcore.Core {
+	res := *c
+	res.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+	return &res
+}
+
+func (c *ZapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *ZapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	tagNames := c.opts.Tags
+	if tagNames == nil {
+		tagNames = defaultFieldTags
+	}
+
+	tags := map[string]string{}
+	if ent.LoggerName != "" {
+		tags["module"] = ent.LoggerName
+	}
+
+	var errArg error
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range append(append([]zapcore.Field(nil), c.fields...), fields...) {
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType && errArg == nil && !sentry.IsNilError(err) {
+			errArg = err
+			continue
+		}
+		f.AddTo(enc)
+	}
+
+	extra := map[string]interface{}{}
+	for key, val := range enc.Fields {
+		if name, ok := tagNames[key]; ok {
+			tags[name] = fmt.Sprint(val)
+			continue
+		}
+		extra[key] = val
+	}
+
+	pc := ent.Caller.PC
+	if !ent.Caller.Defined {
+		pc = callerPC("go.uber.org/zap", "github.com/muravjov/slog.(*ZapCore)")
+	}
+
+	rec := fieldsRecord{
+		Message: ent.Message,
+		Level:   zapSeverities[ent.Level],
+		Time:    ent.Time,
+		PC:      pc,
+		Tags:    tags,
+		Extra:   extra,
+		Err:     errArg,
+	}
+	if !c.reported(ent.Level) {
+		addFieldsBreadcrumb(rec, "zap", enc.Fields)
+		return nil
+	}
+
+	captureFields(context.Background(), c.opts.Client, rec)
+	return nil
+}
+
+// Sync() waits for queued events, see sentry.StartQueue()
+func (c *ZapCore) Sync() error {
+	sentry.Flush(time.Second * 5)
+	return nil
+}
+
+// SetupZap() makes zap global logger, see zap.L(), write entries to logPath, stderr if empty,
+// and report warnings and errors to Sentry
+func SetupZap(logPath string, dsn string) {
+	logWriter := zapcore.Lock(zapcore.AddSync(openLogOrStderr(logPath)))
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+	var core zapcore.Core = zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), logWriter, zapcore.InfoLevel)
+
+	if dsn != "" {
+		slogV2.MustSetDSNAndHandler(dsn)
+		core = zapcore.NewTee(core, NewZapCore(ZapCoreOptions{}))
+	}
+	watcher.StartWatcher(dsn, logPath)
+
+	zap.ReplaceGlobals(zap.New(core, zap.AddCaller()))
+}
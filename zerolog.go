@@ -0,0 +1,148 @@
+package slog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/getsentry/raven-go"
+	"github.com/muravjov/slog/sentry"
+	slogV2 "github.com/muravjov/slog/v2"
+	"github.com/muravjov/slog/watcher"
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// ZerologWriterOptions configures ZerologWriter
+type ZerologWriterOptions struct {
+	// events at or above it are reported, zerolog.WarnLevel if nil;
+	// less severe ones go to breadcrumbs, if they are turned on.
+	// :TRICKY: a pointer, because zero zerolog.Level is DebugLevel
+	Level *zerolog.Level
+	// field key => tag name, other fields go to extra; nil means {"module": "module"}
+	Tags map[string]string
+	// nil means sentry.DefaultClient()
+	Client *sentry.Client
+}
+
+// ZerologWriter writes zerolog events to Writer and reports them to Sentry:
+//
+//	logger := zerolog.New(slog.NewZerologWriter(os.Stderr, slog.ZerologWriterOptions{}))
+//
+// :TRICKY: zerolog hooks can't read event fields, so it's a writer parsing JSON;
+// the error field is a string then, so errors are reported with stacktrace, but
+// not as exceptions
+type ZerologWriter struct {
+	// may be nil
+	Writer io.Writer
+	opts   ZerologWriterOptions
+}
+
+func NewZerologWriter(w io.Writer, opts ZerologWriterOptions) *ZerologWriter {
+	return &ZerologWriter{
+		Writer: w,
+		opts:   opts,
+	}
+}
+
+var zerologSeverities = map[zerolog.Level]raven.Severity{
+	zerolog.TraceLevel: raven.DEBUG,
+	zerolog.DebugLevel: raven.DEBUG,
+	zerolog.InfoLevel:  raven.INFO,
+	zerolog.WarnLevel:  raven.WARNING,
+	zerolog.ErrorLevel: raven.ERROR,
+	zerolog.FatalLevel: raven.FATAL,
+	zerolog.PanicLevel: raven.FATAL,
+}
+
+func (w *ZerologWriter) level() zerolog.Level {
+	if w.opts.Level == nil {
+		return zerolog.WarnLevel
+	}
+	return *w.opts.Level
+}
+
+func (w *ZerologWriter) Write(p []byte) (int, error) {
+	var fields struct {
+		Level string `json:"level"`
+	}
+	json.Unmarshal(p, &fields)
+	level, err := zerolog.ParseLevel(fields.Level)
+	if err != nil {
+		level = zerolog.NoLevel
+	}
+	return w.WriteLevel(level, p)
+}
+
+func (w *ZerologWriter) WriteLevel(level zerolog.Level, p []byte) (n int, err error) {
+	n = len(p)
+	if w.Writer != nil {
+		n, err = w.Writer.Write(p)
+	}
+
+	severity, ok := zerologSeverities[level]
+	if !ok {
+		return
+	}
+	reported := level >= w.level()
+	if !reported && !sentry.BreadcrumbsEnabledFor(severity) {
+		return
+	}
+
+	var fields map[string]interface{}
+	if json.Unmarshal(p, &fields) != nil {
+		return
+	}
+
+	tagNames := w.opts.Tags
+	if tagNames == nil {
+		tagNames = defaultFieldTags
+	}
+
+	message, _ := fields[zerolog.MessageFieldName].(string)
+	tags := map[string]string{}
+	extra := map[string]interface{}{}
+	for key, val := range fields {
+		switch key {
+		case zerolog.LevelFieldName, zerolog.MessageFieldName, zerolog.TimestampFieldName:
+			continue
+		}
+		if name, ok := tagNames[key]; ok {
+			tags[name] = fmt.Sprint(val)
+			continue
+		}
+		extra[key] = val
+	}
+
+	rec := fieldsRecord{
+		Message: message,
+		Level:   severity,
+		Time:    time.Now(),
+		PC:      callerPC("github.com/rs/zerolog", "github.com/muravjov/slog.(*ZerologWriter)"),
+		Tags:    tags,
+		Extra:   extra,
+	}
+	if !reported {
+		addFieldsBreadcrumb(rec, "zerolog", extra)
+		return
+	}
+
+	captureFields(context.Background(), w.opts.Client, rec)
+	return
+}
+
+// SetupZerolog() makes zerolog global logger, see zerolog/log package, write events to
+// logPath, stderr if empty, and report warnings and errors to Sentry
+func SetupZerolog(logPath string, dsn string) {
+	logWriter := openLogOrStderr(logPath)
+
+	if dsn != "" {
+		slogV2.MustSetDSNAndHandler(dsn)
+		logWriter = NewZerologWriter(logWriter, ZerologWriterOptions{})
+	}
+	watcher.StartWatcher(dsn, logPath)
+
+	zlog.Logger = zerolog.New(logWriter).With().Timestamp().Logger()
+}